@@ -8,4 +8,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.31.0
+	golang.org/x/text v0.21.0
 )
+
+require golang.org/x/sys v0.28.0 // indirect
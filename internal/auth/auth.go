@@ -6,55 +6,171 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type TokenType string
 
 const (
 	TokenTypeAccess TokenType = "chirpy-access"
+	// TokenTypeMFA is a challenge token, proving the password was right while a second factor is still owed
+	TokenTypeMFA TokenType = "chirpy-mfa"
 )
 
 var ErrNoAuthHeaderIncluded = errors.New("no auth header included in request")
 
-func HashPassword(password string) (string, error) {
-	dat, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(dat), nil
+// ErrScopedToken is returned for an OAuth client's token where only a token from logging in will do
+var ErrScopedToken = errors.New("scoped tokens can't be used here")
+
+// accessClaims are the claims carried by an access token
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Role Role `json:"role,omitempty"`
+	// tokens issued to an OAuth client also carry the scopes the user granted it, and the session they belong to
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+}
+
+// AccessToken is what a valid access token says about its bearer
+type AccessToken struct {
+	UserID uuid.UUID
+	Role   Role
+	// Scopes limit what the token can be used for. Tokens from logging in have none, and can do anything their user can.
+	Scopes []Scope
+	// ClientID and SessionID are set for tokens issued to an OAuth client
+	ClientID  uuid.UUID
+	SessionID uuid.UUID
+	IssuedAt  time.Time
+	ExpiresAt time.Time
 }
 
-func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+// Allows reports whether the token can be used for something needing the given scope
+func (t AccessToken) Allows(scope Scope) bool {
+	return t.Scopes == nil || slices.Contains(t.Scopes, scope)
 }
 
+// MakeJWT mints an access token, signed with the keyring's active key
 func MakeJWT(
 	userID uuid.UUID,
-	tokenSecret string,
+	role Role,
+	keys *Keyring,
 	expiresIn time.Duration,
 ) (string, error) {
-	signingKey := []byte(tokenSecret)
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    string(TokenTypeAccess),
+	return keys.sign(accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    string(TokenTypeAccess),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Role: role,
+	})
+}
+
+// MakeScopedJWT mints an access token for an OAuth client, which can only be used for the given scopes
+func MakeScopedJWT(
+	userID uuid.UUID,
+	clientID uuid.UUID,
+	sessionID uuid.UUID,
+	scopes []Scope,
+	keys *Keyring,
+	expiresIn time.Duration,
+) (string, error) {
+	return keys.sign(accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    string(TokenTypeAccess),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
+			Subject:   userID.String(),
+		},
+		Role:      RoleUser,
+		Scope:     FormatScopes(scopes),
+		ClientID:  clientID.String(),
+		SessionID: sessionID.String(),
+	})
+}
+
+// ValidateJWT validates an access token from logging in and returns its bearer's ID. Scoped tokens are
+// rejected, so endpoints using it can't be reached by OAuth clients.
+func ValidateJWT(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	token, err := ParseJWT(tokenString, keys)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if token.Scopes != nil {
+		return uuid.Nil, ErrScopedToken
+	}
+	return token.UserID, nil
+}
+
+// ParseJWT validates an access token and returns its bearer's ID and role.
+// Tokens minted before roles were added carry no role, and are treated as RoleUser.
+func ParseJWT(tokenString string, keys *Keyring) (AccessToken, error) {
+	claimsStruct := accessClaims{}
+	id, err := parseToken(tokenString, keys, &claimsStruct, TokenTypeAccess)
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	role := claimsStruct.Role
+	if role == "" {
+		role = RoleUser
+	}
+	token := AccessToken{UserID: id, Role: role}
+	if claimsStruct.IssuedAt != nil {
+		token.IssuedAt = claimsStruct.IssuedAt.Time
+	}
+	if claimsStruct.ExpiresAt != nil {
+		token.ExpiresAt = claimsStruct.ExpiresAt.Time
+	}
+	if claimsStruct.Scope == "" {
+		return token, nil
+	}
+
+	// a scoped token
+	token.Scopes, err = ParseScopeString(claimsStruct.Scope)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	token.ClientID, err = uuid.Parse(claimsStruct.ClientID)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("invalid client ID: %w", err)
+	}
+	token.SessionID, err = uuid.Parse(claimsStruct.SessionID)
+	if err != nil {
+		return AccessToken{}, fmt.Errorf("invalid session ID: %w", err)
+	}
+	return token, nil
+}
+
+// MakeMFAToken mints a challenge token, which can only be exchanged for an access token along with a second factor
+func MakeMFAToken(userID uuid.UUID, keys *Keyring, expiresIn time.Duration) (string, error) {
+	return keys.sign(jwt.RegisteredClaims{
+		Issuer:    string(TokenTypeMFA),
 		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(expiresIn)),
 		Subject:   userID.String(),
 	})
-	return token.SignedString(signingKey)
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claimsStruct := jwt.RegisteredClaims{}
+// ValidateMFAToken validates a challenge token and returns the ID of the user it was issued to
+func ValidateMFAToken(tokenString string, keys *Keyring) (uuid.UUID, error) {
+	return parseToken(tokenString, keys, &jwt.RegisteredClaims{}, TokenTypeMFA)
+}
+
+// parseToken validates a token of the given type into claims, and returns its subject
+func parseToken(tokenString string, keys *Keyring, claims jwt.Claims, tokenType TokenType) (uuid.UUID, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
-		&claimsStruct,
-		func(token *jwt.Token) (interface{}, error) { return []byte(tokenSecret), nil },
+		claims,
+		keys.keyFunc,
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA, AlgHS256}),
 	)
 	if err != nil {
 		return uuid.Nil, err
@@ -69,7 +185,7 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
 	if err != nil {
 		return uuid.Nil, err
 	}
-	if issuer != string(TokenTypeAccess) {
+	if issuer != string(tokenType) {
 		return uuid.Nil, errors.New("invalid issuer")
 	}
 
@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// supported signing algorithms
+const (
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+	AlgHS256 = "HS256"
+)
+
+// verificationKey is a public key that access tokens can be checked against
+type verificationKey struct {
+	id     string
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// Keyring holds the key access tokens are signed with, and every key they are accepted from.
+// Retired keys stay on the ring for verification until the tokens they signed have expired,
+// so rotating keys never invalidates a live token.
+type Keyring struct {
+	activeID  string
+	active    crypto.Signer // nil when signing with the shared secret
+	verifiers map[string]verificationKey
+
+	// secret is the legacy HS256 secret. Tokens without a kid are checked against it, and it
+	// signs new tokens when there are no asymmetric keys.
+	secret []byte
+}
+
+// NewHMACKeyring signs and verifies tokens with a single shared HS256 secret
+func NewHMACKeyring(secret string) *Keyring {
+	return &Keyring{
+		verifiers: map[string]verificationKey{},
+		secret:    []byte(secret),
+	}
+}
+
+// LoadKeyring reads every PEM key in dir, named <kid>.pem, and signs with the one named activeID.
+// Private keys (PKCS #8) can sign and verify, public keys (PKIX) can only verify. If legacySecret is
+// set, HS256 tokens issued before the switch to asymmetric keys are still accepted.
+func LoadKeyring(dir, activeID, legacySecret string) (*Keyring, error) {
+	if activeID == "" {
+		return nil, errors.New("no active key id given")
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	k := &Keyring{
+		activeID:  activeID,
+		verifiers: map[string]verificationKey{},
+		secret:    []byte(legacySecret),
+	}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		signer, public, err := readKeyFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		method, err := signingMethodFor(public)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		k.verifiers[kid] = verificationKey{id: kid, method: method, public: public}
+		if kid == activeID {
+			if signer == nil {
+				return nil, fmt.Errorf("%s: the active key must be a private key", path)
+			}
+			k.active = signer
+		}
+	}
+	if k.active == nil {
+		return nil, fmt.Errorf("active key %s not found in %s", activeID, dir)
+	}
+	return k, nil
+}
+
+// sign mints a token with the active key, naming it in the kid header
+func (k *Keyring) sign(claims jwt.Claims) (string, error) {
+	if k.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.secret)
+	}
+
+	key := k.verifiers[k.activeID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = k.activeID
+	return token.SignedString(k.active)
+}
+
+// keyFunc picks the key to check a token against by its kid. The key decides the
+// algorithm, so a token can't choose a weaker one for itself.
+func (k *Keyring) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if len(k.secret) == 0 {
+			return nil, errors.New("token has no kid")
+		}
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return k.secret, nil
+	}
+
+	key, ok := k.verifiers[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %s", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %s", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists every public key on the ring, so other services can verify tokens without
+// holding any secrets. The shared HS256 secret is never published.
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.verifiers {
+		jwk := JWK{KeyID: key.id, Use: "sig", Algorithm: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
+
+// GenerateKey writes a new private key to dir as <kid>.pem, for an alg of RS256 or EdDSA
+func GenerateKey(dir, kid, alg string) (string, error) {
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", fmt.Errorf("unsupported algorithm %q: must be %s or %s", alg, AlgRS256, AlgEdDSA)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+
+	// never overwrite a key that may still be verifying tokens
+	path := filepath.Join(dir, kid+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	err = pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// readKeyFile parses a PEM private or public key. The signer is nil for public keys.
+func readKeyFile(path string) (crypto.Signer, crypto.PublicKey, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, _ := pem.Decode(dat)
+	if block == nil {
+		return nil, nil, errors.New("no PEM data found")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, nil, errors.New("unsupported private key type")
+		}
+		return signer, signer.Public(), nil
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, key, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+func signingMethodFor(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+}
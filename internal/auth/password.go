@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrPasswordMismatch = errors.New("password does not match hash")
+
+// Argon2idParams are the cost parameters of an Argon2id hash
+type Argon2idParams struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2idParams are the parameters new hashes are made with - the second recommended
+// option of RFC 9106. Hashes made with anything else are upgraded on the user's next login.
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// HashPassword hashes a password with Argon2id, encoded in PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	p := DefaultArgon2idParams
+	salt := make([]byte, p.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPasswordHash checks a password against a hash, working out from the hash which algorithm
+// and parameters made it. Argon2id and bcrypt hashes are understood.
+func CheckPasswordHash(password, hash string) error {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return checkArgon2id(password, hash)
+	case isBcrypt(hash):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrPasswordMismatch
+		}
+		return err
+	default:
+		return errors.New("unrecognised password hash")
+	}
+}
+
+// PasswordNeedsRehash reports whether a hash was made by an older algorithm or with other
+// parameters than HashPassword uses now, so should be replaced once the password is known
+func PasswordNeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2id(hash)
+	return err != nil || params != DefaultArgon2idParams
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func checkArgon2id(password, hash string) error {
+	p, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// decodeArgon2id parses a PHC string made by HashPassword
+func decodeArgon2id(hash string) (Argon2idParams, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	p := Argon2idParams{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
+
+// maxPasswordBytes is bcrypt's limit, kept so that no password means more than its hash can hold
+const maxPasswordBytes = 72
+
+// PasswordPolicy decides which new passwords are acceptable
+type PasswordPolicy struct {
+	MinLength int // in characters
+	common    map[string]struct{}
+}
+
+// NewPasswordPolicy rejects passwords shorter than minLength characters, and any found in
+// the common passwords file if a path is given - one password per line, ignoring case
+func NewPasswordPolicy(minLength int, commonPasswordsPath string) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{MinLength: minLength, common: map[string]struct{}{}}
+	if commonPasswordsPath == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(commonPasswordsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			policy.common[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate returns why a password isn't acceptable, if it isn't
+func (p *PasswordPolicy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must be at most %d bytes", maxPasswordBytes)
+	}
+	if _, ok := p.common[strings.ToLower(password)]; ok {
+		return errors.New("password is too common")
+	}
+	return nil
+}
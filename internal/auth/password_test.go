@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fixedArgon2idHash is "password" hashed with the salt "somesalt", t=2, m=64MiB and p=4. It is fixed,
+// so a change to how PHC strings are encoded or parsed can't go unnoticed by round-tripping alone.
+const fixedArgon2idHash = "$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$GpZ3sK/oH9p7VIiV56G/64Zo/8GaUw434IimaPqxwCo"
+
+func TestCheckPasswordHash(t *testing.T) {
+	current, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword error: %v", err)
+	}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		hash     string
+		wantErr  error
+	}{
+		{"argon2id", "password", current, nil},
+		{"argon2id wrong password", "Password", current, ErrPasswordMismatch},
+		{"argon2id fixed hash", "password", fixedArgon2idHash, nil},
+		{"argon2id fixed hash wrong password", "passwore", fixedArgon2idHash, ErrPasswordMismatch},
+		{"bcrypt", "password", string(legacy), nil},
+		{"bcrypt wrong password", "Password", string(legacy), ErrPasswordMismatch},
+	}
+
+	for _, tt := range tests {
+		err := CheckPasswordHash(tt.password, tt.hash)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckPasswordHashMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"empty", ""},
+		{"plain text", "password"},
+		{"unknown algorithm", "$scrypt$ln=15,r=8,p=1$c29tZXNhbHQ$aGFzaA"},
+		{"missing fields", "$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ"},
+		{"other version", "$argon2id$v=16$m=65536,t=2,p=4$c29tZXNhbHQ$GpZ3sK/oH9p7VIiV56G/64Zo/8GaUw434IimaPqxwCo"},
+		{"bad parameters", "$argon2id$v=19$m=lots,t=2,p=4$c29tZXNhbHQ$GpZ3sK/oH9p7VIiV56G/64Zo/8GaUw434IimaPqxwCo"},
+		{"bad salt", "$argon2id$v=19$m=65536,t=2,p=4$not base64!$GpZ3sK/oH9p7VIiV56G/64Zo/8GaUw434IimaPqxwCo"},
+		{"bad key", "$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$not base64!"},
+	}
+
+	for _, tt := range tests {
+		err := CheckPasswordHash("password", tt.hash)
+		if err == nil || errors.Is(err, ErrPasswordMismatch) {
+			t.Errorf("%s: got error %v, want a malformed hash error", tt.name, err)
+		}
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	current, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("HashPassword error: %v", err)
+	}
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("bcrypt error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"current parameters", current, false},
+		{"other parameters", fixedArgon2idHash, true},
+		{"bcrypt", string(legacy), true},
+		{"malformed", "$argon2id$v=19$", true},
+	}
+
+	for _, tt := range tests {
+		if got := PasswordNeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("%s: PasswordNeedsRehash = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "common.txt")
+	err := os.WriteFile(path, []byte("password123\n\n  Letmein!  \n"), 0o600)
+	if err != nil {
+		t.Fatalf("writing common passwords: %v", err)
+	}
+	policy, err := NewPasswordPolicy(8, path)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"long enough", "correct horse", false},
+		{"exactly the minimum", "abcdefgh", false},
+		{"too short", "abcdefg", true},
+		// length is counted in characters, not bytes
+		{"short in characters", "ééééééé", true},
+		{"long enough in characters", "éééééééé", false},
+		{"at the byte limit", strings.Repeat("a", maxPasswordBytes), false},
+		{"over the byte limit", strings.Repeat("a", maxPasswordBytes+1), true},
+		{"common", "password123", true},
+		{"common ignoring case", "LETMEIN!", true},
+	}
+
+	for _, tt := range tests {
+		err := policy.Validate(tt.password)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewPasswordPolicyMissingFile(t *testing.T) {
+	_, err := NewPasswordPolicy(8, filepath.Join(t.TempDir(), "missing.txt"))
+	if err == nil {
+		t.Error("expected an error for a missing common passwords file")
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+// PKCE (RFC 7636) - only the S256 method is supported, as plain offers no protection if the challenge leaks
+const PKCEMethodS256 = "S256"
+
+var (
+	pkceVerifierPattern  = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+	pkceChallengePattern = regexp.MustCompile(`^[A-Za-z0-9\-_]{43}$`)
+)
+
+// PKCEChallenge derives the S256 code challenge for a code verifier
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ValidPKCEChallenge reports whether a code challenge could have been made by PKCEChallenge
+func ValidPKCEChallenge(challenge string) bool {
+	return pkceChallengePattern.MatchString(challenge)
+}
+
+// VerifyPKCE reports whether a code verifier matches the challenge it was sent with earlier
+func VerifyPKCE(verifier, challenge string) bool {
+	if !pkceVerifierPattern.MatchString(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(PKCEChallenge(verifier)), []byte(challenge)) == 1
+}
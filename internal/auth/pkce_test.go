@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// the S256 example from RFC 7636 Appendix B
+const (
+	rfc7636Verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfc7636Challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestPKCEChallengeRFC7636(t *testing.T) {
+	if got := PKCEChallenge(rfc7636Verifier); got != rfc7636Challenge {
+		t.Errorf("PKCEChallenge = %s, want %s", got, rfc7636Challenge)
+	}
+}
+
+func TestValidPKCEChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		want      bool
+	}{
+		{"rfc example", rfc7636Challenge, true},
+		{"too short", rfc7636Challenge[:42], false},
+		{"too long", rfc7636Challenge + "A", false},
+		{"padded", rfc7636Challenge[:42] + "=", false},
+		{"standard base64", strings.Replace(rfc7636Challenge, "-", "+", 1), false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidPKCEChallenge(tt.challenge); got != tt.want {
+			t.Errorf("%s: ValidPKCEChallenge(%q) = %v, want %v", tt.name, tt.challenge, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	long := strings.Repeat("a", 128)
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"rfc example", rfc7636Verifier, rfc7636Challenge, true},
+		{"wrong verifier", strings.Replace(rfc7636Verifier, "d", "e", 1), rfc7636Challenge, false},
+		{"longest verifier", long, PKCEChallenge(long), true},
+		{"verifier too short", long[:42], PKCEChallenge(long[:42]), false},
+		{"verifier too long", long + "a", PKCEChallenge(long + "a"), false},
+		{"verifier with invalid characters", rfc7636Verifier[:42] + "+", PKCEChallenge(rfc7636Verifier[:42] + "+"), false},
+		// the plain method isn't supported, so the verifier can't be its own challenge
+		{"plain method", rfc7636Verifier, rfc7636Verifier, false},
+	}
+
+	for _, tt := range tests {
+		if got := VerifyPKCE(tt.verifier, tt.challenge); got != tt.want {
+			t.Errorf("%s: VerifyPKCE = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import "fmt"
+
+// Role is a user's level of access. Each role can do everything the roles below it can.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRanks = map[Role]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// ParseRole validates a role read from the database or a request
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("invalid role %q: must be user, moderator or admin", s)
+	}
+	return role, nil
+}
+
+// Has reports whether the role grants at least the access of another
+func (r Role) Has(required Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[required]
+}
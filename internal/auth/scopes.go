@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scope is one thing a personal access token or OAuth client may be used for
+type Scope string
+
+const (
+	ScopeChirpsRead   Scope = "chirps:read"
+	ScopeChirpsWrite  Scope = "chirps:write"
+	ScopeProfileWrite Scope = "profile:write"
+)
+
+// Scopes are all the scopes a token can be given
+var Scopes = []Scope{ScopeChirpsRead, ScopeChirpsWrite, ScopeProfileWrite}
+
+// ParseScopes validates scopes read from the database or a request, dropping duplicates
+func ParseScopes(s []string) ([]Scope, error) {
+	scopes := []Scope{}
+	for _, name := range s {
+		scope := Scope(name)
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("invalid scope %q: must be one of %s", name, strings.Join(ScopeStrings(Scopes), ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// ParseScopeString validates a space separated list of scopes, as OAuth sends them
+func ParseScopeString(s string) ([]Scope, error) {
+	return ParseScopes(strings.Fields(s))
+}
+
+// FormatScopes joins scopes into a space separated list, as OAuth sends them
+func FormatScopes(scopes []Scope) string {
+	return strings.Join(ScopeStrings(scopes), " ")
+}
+
+// ScopeStrings converts scopes back to the strings they are stored as
+func ScopeStrings(scopes []Scope) []string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return s
+}
+
+// PersonalAccessTokenPrefix starts every personal access token, so they can be told apart from
+// JWTs without a database lookup, and spotted by secret scanners when they leak
+const PersonalAccessTokenPrefix = "chirpy_pat_"
+
+// MakePersonalAccessToken creates a new personal access token
+func MakePersonalAccessToken() (string, error) {
+	token, err := MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return PersonalAccessTokenPrefix + token, nil
+}
+
+// IsPersonalAccessToken reports whether a bearer token looks like a personal access token
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      []string
+		want    []Scope
+		wantErr bool
+	}{
+		{"none", nil, []Scope{}, false},
+		{"one", []string{"chirps:read"}, []Scope{ScopeChirpsRead}, false},
+		{"all", []string{"chirps:read", "chirps:write", "profile:write"}, Scopes, false},
+		{"duplicates dropped", []string{"chirps:write", "chirps:read", "chirps:write"}, []Scope{ScopeChirpsWrite, ScopeChirpsRead}, false},
+		{"unknown", []string{"chirps:read", "admin"}, nil, true},
+		{"case matters", []string{"Chirps:Read"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseScopes(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseScopeString(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []Scope
+		wantErr bool
+	}{
+		{"", []Scope{}, false},
+		{"chirps:read", []Scope{ScopeChirpsRead}, false},
+		{"  chirps:read   profile:write ", []Scope{ScopeChirpsRead, ScopeProfileWrite}, false},
+		{"chirps:read,profile:write", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseScopeString(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseScopeString(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseScopeString(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		// formatting is the inverse of parsing
+		if err == nil && FormatScopes(got) != strings.Join(strings.Fields(tt.in), " ") {
+			t.Errorf("FormatScopes(%v) = %q, want %q", got, FormatScopes(got), tt.in)
+		}
+	}
+}
+
+func TestAccessTokenAllows(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []Scope
+		scope  Scope
+		want   bool
+	}{
+		// login tokens carry no scopes, and can do anything their user can
+		{"login token", nil, ScopeProfileWrite, true},
+		{"granted", []Scope{ScopeChirpsRead, ScopeChirpsWrite}, ScopeChirpsWrite, true},
+		{"not granted", []Scope{ScopeChirpsRead}, ScopeChirpsWrite, false},
+		{"empty grant", []Scope{}, ScopeChirpsRead, false},
+	}
+
+	for _, tt := range tests {
+		token := AccessToken{Scopes: tt.scopes}
+		if got := token.Allows(tt.scope); got != tt.want {
+			t.Errorf("%s: Allows(%s) = %v, want %v", tt.name, tt.scope, got, tt.want)
+		}
+	}
+}
+
+func TestPersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	if err != nil {
+		t.Fatalf("MakePersonalAccessToken error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{"made token", token, true},
+		{"jwt", "eyJhbGciOiJFZERTQSJ9.e30.c2ln", false},
+		{"refresh token", strings.TrimPrefix(token, PersonalAccessTokenPrefix), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPersonalAccessToken(tt.token); got != tt.want {
+			t.Errorf("%s: IsPersonalAccessToken = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238) - the defaults every authenticator app understands
+const (
+	totpPeriod = 30 // seconds
+	totpDigits = 6
+	// totpSkew is how many periods either side of now a code is accepted from, to allow for clock drift
+	totpSkew = 1
+)
+
+var (
+	ErrInvalidTOTP = errors.New("invalid TOTP code")
+	ErrReusedTOTP  = errors.New("TOTP code already used")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret creates a random 160 bit secret, base32 encoded as authenticator apps expect
+func GenerateTOTPSecret() (string, error) {
+	dat := make([]byte, 20)
+	_, err := rand.Read(dat)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(dat), nil
+}
+
+// TOTPURI builds the otpauth:// URI that authenticator apps enrol from, usually shown as a QR code
+func TOTPURI(secret, issuer, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}
+
+// TOTPCode returns the code for the period containing t
+func TOTPCode(secret string, t time.Time) (string, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, totpCounter(t)), nil
+}
+
+// ValidateTOTP checks a code against the periods around t, and returns the counter of the period it
+// matched. Callers should only accept a counter newer than the last one they accepted, so codes can't be replayed.
+func ValidateTOTP(secret, code string, t time.Time) (int64, error) {
+	key, err := decodeTOTPSecret(secret)
+	if err != nil {
+		return 0, err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, ErrInvalidTOTP
+	}
+
+	now := totpCounter(t)
+	for step := -totpSkew; step <= totpSkew; step++ {
+		counter := now + uint64(step)
+		if subtle.ConstantTimeCompare([]byte(hotp(key, counter)), []byte(code)) == 1 {
+			return int64(counter), nil
+		}
+	}
+	return 0, ErrInvalidTOTP
+}
+
+// ValidateTOTPAfter is ValidateTOTP for a user who has signed in before, only accepting a counter newer
+// than lastCounter. Pass -1 if no code has been accepted yet.
+func ValidateTOTPAfter(secret, code string, t time.Time, lastCounter int64) (int64, error) {
+	counter, err := ValidateTOTP(secret, code, t)
+	if err != nil {
+		return 0, err
+	}
+	if counter <= lastCounter {
+		return 0, ErrReusedTOTP
+	}
+	return counter, nil
+}
+
+func totpCounter(t time.Time) uint64 {
+	return uint64(t.Unix() / totpPeriod)
+}
+
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+	return key, nil
+}
+
+// hotp computes an HOTP code (RFC 4226) for a counter
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%mod)
+}
+
+// recovery codes are 16 characters (80 bits) of lowercase base32, shown in groups of four for readability
+var recoveryEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// GenerateRecoveryCodes creates n one-time codes, for signing in when the authenticator is lost
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		dat := make([]byte, 10)
+		_, err := rand.Read(dat)
+		if err != nil {
+			return nil, err
+		}
+		code := recoveryEncoding.EncodeToString(dat)
+		codes = append(codes, code[:4]+"-"+code[4:8]+"-"+code[8:12]+"-"+code[12:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode puts a recovery code as typed into the form it is hashed in
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// HashToken hashes a random secret token for storage. Tokens are long and random, so unlike
+// passwords they don't need a slow hash, and can be looked up by their hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
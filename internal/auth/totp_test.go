@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed from RFC 6238 Appendix B, "12345678901234567890", base32 encoded
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// the RFC lists 8 digit codes - ours are the last 6 of them
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d) error: %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	period := totpPeriod * time.Second
+
+	tests := []struct {
+		name    string
+		offset  time.Duration
+		wantErr bool
+	}{
+		{"current period", 0, false},
+		{"one period before", -period, false},
+		{"one period after", period, false},
+		{"two periods before", -2 * period, true},
+		{"two periods after", 2 * period, true},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfc6238Secret, now.Add(tt.offset))
+		if err != nil {
+			t.Fatalf("%s: TOTPCode error: %v", tt.name, err)
+		}
+
+		counter, err := ValidateTOTP(rfc6238Secret, code, now)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidTOTP) {
+				t.Errorf("%s: got error %v, want %v", tt.name, err, ErrInvalidTOTP)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if want := int64(totpCounter(now.Add(tt.offset))); counter != want {
+			t.Errorf("%s: counter = %d, want %d", tt.name, counter, want)
+		}
+	}
+}
+
+func TestValidateTOTPAfterRejectsReuse(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code, err := TOTPCode(rfc6238Secret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode error: %v", err)
+	}
+
+	counter, err := ValidateTOTPAfter(rfc6238Secret, code, now, -1)
+	if err != nil {
+		t.Fatalf("first use: unexpected error: %v", err)
+	}
+
+	_, err = ValidateTOTPAfter(rfc6238Secret, code, now, counter)
+	if !errors.Is(err, ErrReusedTOTP) {
+		t.Errorf("second use: got error %v, want %v", err, ErrReusedTOTP)
+	}
+
+	// an older code still inside the window was superseded by the newer one
+	previous, err := TOTPCode(rfc6238Secret, now.Add(-totpPeriod*time.Second))
+	if err != nil {
+		t.Fatalf("TOTPCode error: %v", err)
+	}
+	_, err = ValidateTOTPAfter(rfc6238Secret, previous, now, counter)
+	if !errors.Is(err, ErrReusedTOTP) {
+		t.Errorf("older code: got error %v, want %v", err, ErrReusedTOTP)
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes error: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("got %d codes, want 10", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		normalized := NormalizeRecoveryCode(code)
+		// 16 base32 characters carry 80 bits
+		if len(normalized) != 16 {
+			t.Errorf("code %q has %d characters, want 16", code, len(normalized))
+		}
+		if NormalizeRecoveryCode(strings.ToUpper(code)) != normalized {
+			t.Errorf("code %q doesn't normalize case", code)
+		}
+		if seen[normalized] {
+			t.Errorf("code %q repeated", code)
+		}
+		seen[normalized] = true
+	}
+}
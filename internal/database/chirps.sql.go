@@ -7,25 +7,81 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps WHERE chirps.parent_id = $1::uuid
+)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, chirpID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, chirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, root_id, kind, quote_of_id, moderation_status)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body             string
+	UserID           uuid.UUID
+	ParentID         uuid.NullUUID
+	RootID           uuid.NullUUID
+	Kind             string
+	QuoteOfID        uuid.NullUUID
+	ModerationStatus string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.ParentID, arg.RootID, arg.Kind, arg.QuoteOfID, arg.ModerationStatus)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.BodyTsv,
+		&i.ModerationStatus,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, kind, rechirp_of_id)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), '', $1, 'rechirp', $2
+)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status
+`
+
+type CreateRechirpParams struct {
+	UserID      uuid.UUID
+	RechirpOfID uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOfID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -33,6 +89,15 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.BodyTsv,
+		&i.ModerationStatus,
 	)
 	return i, err
 }
@@ -46,12 +111,46 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY chirps.created_at ASC
+const getChirp = `-- name: GetChirp :one
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status FROM chirps WHERE chirps.id = $1
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.BodyTsv,
+		&i.ModerationStatus,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, parent_id) AS (
+    SELECT c.id, c.parent_id FROM chirps c WHERE c.id = $1
+    UNION ALL
+    SELECT c.id, c.parent_id FROM chirps c JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.like_count, chirps.kind, chirps.rechirp_of_id, chirps.quote_of_id, chirps.body_tsv, chirps.moderation_status FROM chirps
+WHERE chirps.id IN (SELECT ancestors.id FROM ancestors)
+  AND chirps.id <> $1
+ORDER BY chirps.created_at ASC, chirps.id ASC
+`
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, chirpID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, chirpID)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +164,15 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -79,12 +187,440 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE chirps.id = $1
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status FROM chirps WHERE chirps.id = ANY($1::uuid[])
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpDescendantsAsc = `-- name: ListChirpDescendantsAsc :many
+WITH RECURSIVE descendants(id) AS (
+    SELECT c.id FROM chirps c WHERE c.parent_id = $1::uuid
+    UNION ALL
+    SELECT c.id FROM chirps c JOIN descendants d ON c.parent_id = d.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.like_count, chirps.kind, chirps.rechirp_of_id, chirps.quote_of_id, chirps.body_tsv, chirps.moderation_status FROM chirps
+WHERE chirps.id IN (SELECT descendants.id FROM descendants)
+  AND chirps.moderation_status = 'visible'
+  AND (
+    $2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > ($2::timestamp, $3::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $4
+`
+
+type ListChirpDescendantsAscParams struct {
+	ChirpID         uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListChirpDescendantsAsc(ctx context.Context, arg ListChirpDescendantsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpDescendantsAsc, arg.ChirpID, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpDescendantsDesc = `-- name: ListChirpDescendantsDesc :many
+WITH RECURSIVE descendants(id) AS (
+    SELECT c.id FROM chirps c WHERE c.parent_id = $1::uuid
+    UNION ALL
+    SELECT c.id FROM chirps c JOIN descendants d ON c.parent_id = d.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.parent_id, chirps.root_id, chirps.deleted_at, chirps.like_count, chirps.kind, chirps.rechirp_of_id, chirps.quote_of_id, chirps.body_tsv, chirps.moderation_status FROM chirps
+WHERE chirps.id IN (SELECT descendants.id FROM descendants)
+  AND chirps.moderation_status = 'visible'
+  AND (
+    $2::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $4
+`
+
+type ListChirpDescendantsDescParams struct {
+	ChirpID         uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListChirpDescendantsDesc(ctx context.Context, arg ListChirpDescendantsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpDescendantsDesc, arg.ChirpID, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status FROM chirps
+WHERE chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = $1::uuid
+    OR $2::boolean
+  )
+  AND (
+    $3::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > ($3::timestamp, $4::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $5
+`
+
+type ListChirpsAscParams struct {
+	ViewerID        uuid.NullUUID
+	IncludeHidden   bool
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc, arg.ViewerID, arg.IncludeHidden, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByAuthorAsc = `-- name: ListChirpsByAuthorAsc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status FROM chirps
+WHERE chirps.user_id = $1
+  AND chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = $2::uuid
+    OR $3::boolean
+  )
+  AND (
+    $4::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > ($4::timestamp, $5::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $6
+`
+
+type ListChirpsByAuthorAscParams struct {
+	AuthorID        uuid.UUID
+	ViewerID        uuid.NullUUID
+	IncludeHidden   bool
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListChirpsByAuthorAsc(ctx context.Context, arg ListChirpsByAuthorAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByAuthorAsc, arg.AuthorID, arg.ViewerID, arg.IncludeHidden, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsByAuthorDesc = `-- name: ListChirpsByAuthorDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status FROM chirps
+WHERE chirps.user_id = $1
+  AND chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = $2::uuid
+    OR $3::boolean
+  )
+  AND (
+    $4::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($4::timestamp, $5::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $6
+`
+
+type ListChirpsByAuthorDescParams struct {
+	AuthorID        uuid.UUID
+	ViewerID        uuid.NullUUID
+	IncludeHidden   bool
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListChirpsByAuthorDesc(ctx context.Context, arg ListChirpsByAuthorDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsByAuthorDesc, arg.AuthorID, arg.ViewerID, arg.IncludeHidden, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status FROM chirps
+WHERE chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = $1::uuid
+    OR $2::boolean
+  )
+  AND (
+    $3::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < ($3::timestamp, $4::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $5
+`
+
+type ListChirpsDescParams struct {
+	ViewerID        uuid.NullUUID
+	IncludeHidden   bool
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	RowLimit        int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc, arg.ViewerID, arg.IncludeHidden, arg.CursorCreatedAt, arg.CursorID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ParentID,
+			&i.RootID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.Kind,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.BodyTsv,
+			&i.ModerationStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpModerationStatus = `-- name: SetChirpModerationStatus :one
+UPDATE chirps
+SET
+    moderation_status = $2,
+    updated_at = NOW()
+WHERE chirps.id = $1
+RETURNING id, created_at, updated_at, body, user_id, parent_id, root_id, deleted_at, like_count, kind, rechirp_of_id, quote_of_id, body_tsv, moderation_status
+`
+
+type SetChirpModerationStatusParams struct {
+	ID               uuid.UUID
+	ModerationStatus string
+}
+
+func (q *Queries) SetChirpModerationStatus(ctx context.Context, arg SetChirpModerationStatusParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpModerationStatus, arg.ID, arg.ModerationStatus)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -92,6 +628,29 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ParentID,
+		&i.RootID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.Kind,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.BodyTsv,
+		&i.ModerationStatus,
 	)
 	return i, err
 }
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET
+    body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE chirps.id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
@@ -6,33 +6,185 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type AuditEvent struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ActorID    uuid.NullUUID
+	Action     string
+	TargetType string
+	TargetID   string
+	Ip         string
+	UserAgent  string
+	Diff       json.RawMessage
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpMention struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
-	ID        uuid.UUID
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Body             string
+	UserID           uuid.UUID
+	ParentID         uuid.NullUUID
+	RootID           uuid.NullUUID
+	DeletedAt        sql.NullTime
+	LikeCount        int32
+	Kind             string
+	RechirpOfID      uuid.NullUUID
+	QuoteOfID        uuid.NullUUID
+	BodyTsv          interface{}
+	ModerationStatus string
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
 	UserID    uuid.UUID
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type LoginIpFailure struct {
+	Ip           string
+	FailedCount  int32
+	LastFailedAt time.Time
+}
+
+type ModerationWord struct {
+	Word      string
+	Action    string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	CreatedAt     time.Time
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	FamilyID      uuid.UUID
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	OwnerID      uuid.UUID
+	Name         string
+	RedirectUris []string
+	SecretHash   sql.NullString
+}
+
+type PasswordResetToken struct {
+	TokenHash string
+	CreatedAt time.Time
 	UserID    uuid.UUID
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token       string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	RevokedAt   sql.NullTime
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	RotatedAt   sql.NullTime
+	UserAgent   string
+	Ip          string
+	LastUsedAt  time.Time
+	ClientID    uuid.NullUUID
+	Scopes      []string
+}
+
+type Report struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ChirpID    uuid.UUID
+	ReporterID uuid.NullUUID
+	Reason     string
+	Details    string
+	Status     string
+	ResolvedAt sql.NullTime
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsPremium      bool
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Email             string
+	HashedPassword    string
+	IsPremium         bool
+	Handle            sql.NullString
+	DisplayName       string
+	Bio               string
+	SuspendedAt       sql.NullTime
+	Role              string
+	TotpSecret        sql.NullString
+	TotpEnabledAt     sql.NullTime
+	TotpLastCounter   sql.NullInt64
+	EmailVerifiedAt   sql.NullTime
+	PendingEmail      sql.NullString
+	FailedLoginCount  int32
+	LastFailedLoginAt sql.NullTime
+	LockedUntil       sql.NullTime
 }
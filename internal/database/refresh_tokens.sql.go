@@ -8,26 +8,75 @@ package database
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const createOAuthRefreshToken = `-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, family_id, parent_token, user_agent, ip, last_used_at, client_id, scopes)
+VALUES (
+    $1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, NOW(), $8, $9
+)
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, rotated_at, user_agent, ip, last_used_at, client_id, scopes
+`
+
+type CreateOAuthRefreshTokenParams struct {
+	Token       string
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	UserAgent   string
+	Ip          string
+	ClientID    uuid.NullUUID
+	Scopes      []string
+}
+
+func (q *Queries) CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt, arg.FamilyID, arg.ParentToken, arg.UserAgent, arg.Ip, arg.ClientID, pq.Array(arg.Scopes))
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, user_agent, ip, last_used_at)
 VALUES (
-    $1, NOW(), NOW(), $2, NOW() + INTERVAL '60 days', $3
+    $1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, $8, NOW()
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, rotated_at, user_agent, ip, last_used_at, client_id, scopes
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
-	UserID    uuid.UUID
-	RevokedAt sql.NullTime
+	Token       string
+	UserID      uuid.UUID
+	ExpiresAt   time.Time
+	RevokedAt   sql.NullTime
+	FamilyID    uuid.UUID
+	ParentToken sql.NullString
+	UserAgent   string
+	Ip          string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.RevokedAt)
+	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt, arg.RevokedAt, arg.FamilyID, arg.ParentToken, arg.UserAgent, arg.Ip)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -36,12 +85,20 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, rotated_at, user_agent, ip, last_used_at, client_id, scopes FROM refresh_tokens
 WHERE refresh_tokens.token = $1
 `
 
@@ -55,10 +112,71 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
+const listUserSessions = `-- name: ListUserSessions :many
+SELECT
+    refresh_tokens.family_id, refresh_tokens.client_id, refresh_tokens.user_agent, refresh_tokens.ip, refresh_tokens.last_used_at, refresh_tokens.expires_at, (
+        SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id
+    )::timestamp AS signed_in_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC
+`
+
+type ListUserSessionsRow struct {
+	FamilyID   uuid.UUID
+	ClientID   uuid.NullUUID
+	UserAgent  string
+	Ip         string
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	SignedInAt time.Time
+}
+
+func (q *Queries) ListUserSessions(ctx context.Context, userID uuid.UUID) ([]ListUserSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUserSessionsRow
+	for rows.Next() {
+		var i ListUserSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.ClientID,
+			&i.UserAgent,
+			&i.Ip,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.SignedInAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const revokeToken = `-- name: RevokeToken :one
 UPDATE refresh_tokens
 SET 
@@ -66,7 +184,7 @@ SET
     updated_at = NOW()
 WHERE token = $1
   AND revoked_at IS NULL
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, rotated_at, user_agent, ip, last_used_at, client_id, scopes
 `
 
 func (q *Queries) RevokeToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -79,6 +197,115 @@ func (q *Queries) RevokeToken(ctx context.Context, token string) (RefreshToken,
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
+const revokeTokenFamily = `-- name: RevokeTokenFamily :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.family_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+`
+
+func (q *Queries) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeTokenFamily, familyID)
+	return err
+}
+
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.family_id = $1
+  AND refresh_tokens.user_id = $2
+  AND refresh_tokens.revoked_at IS NULL
+`
+
+type RevokeUserSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserTokens = `-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeUserTokens, userID)
+	return err
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    rotated_at = NOW(),
+    last_used_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.token = $1
+  AND refresh_tokens.revoked_at IS NULL
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, rotated_at, user_agent, ip, last_used_at, client_id, scopes
+`
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ParentToken,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.Ip,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
+
+const tokenFamilyActive = `-- name: TokenFamilyActive :one
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = $1
+      AND refresh_tokens.revoked_at IS NULL
+      AND refresh_tokens.expires_at > NOW()
+)
+`
+
+func (q *Queries) TokenFamilyActive(ctx context.Context, familyID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, tokenFamilyActive, familyID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
@@ -7,25 +7,109 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const clearFailedLogins = `-- name: ClearFailedLogins :one
+UPDATE users
+SET
+    failed_login_count = 0,
+    last_failed_login_at = NULL,
+    locked_until = NULL
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+func (q *Queries) ClearFailedLogins(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, clearFailedLogins, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const confirmPendingEmail = `-- name: ConfirmPendingEmail :one
+UPDATE users
+SET
+    email = pending_email,
+    pending_email = NULL,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE users.id = $1
+  AND users.pending_email = $2::text
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type ConfirmPendingEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) ConfirmPendingEmail(ctx context.Context, arg ConfirmPendingEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, confirmPendingEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_premium
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -34,6 +118,19 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
@@ -47,8 +144,88 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 	return err
 }
 
+const disableTOTP = `-- name: DisableTOTP :one
+UPDATE users
+SET
+    totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_counter = NULL,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+func (q *Queries) DisableTOTP(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, disableTOTP, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const enableTOTP = `-- name: EnableTOTP :one
+UPDATE users
+SET
+    totp_enabled_at = NOW(),
+    totp_last_counter = $2,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type EnableTOTPParams struct {
+	ID              uuid.UUID
+	TotpLastCounter sql.NullInt64
+}
+
+func (q *Queries) EnableTOTP(ctx context.Context, arg EnableTOTPParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableTOTP, arg.ID, arg.TotpLastCounter)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
 const getAllUsers = `-- name: GetAllUsers :many
-SELECT id, created_at, updated_at, email, hashed_password, is_premium FROM users ORDER BY users.created_at ASC
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until FROM users ORDER BY users.created_at ASC
 `
 
 func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
@@ -67,6 +244,19 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
 			&i.Email,
 			&i.HashedPassword,
 			&i.IsPremium,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.SuspendedAt,
+			&i.Role,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastCounter,
+			&i.EmailVerifiedAt,
+			&i.PendingEmail,
+			&i.FailedLoginCount,
+			&i.LastFailedLoginAt,
+			&i.LockedUntil,
 		); err != nil {
 			return nil, err
 		}
@@ -82,7 +272,7 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, created_at, updated_at, email, hashed_password, is_premium FROM users WHERE users.email = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until FROM users WHERE users.email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -95,12 +285,56 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until FROM users WHERE LOWER(users.handle) = LOWER($1::text)
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, created_at, updated_at, email, hashed_password, is_premium FROM users WHERE users.id = $1
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until FROM users WHERE users.id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -113,29 +347,345 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+SELECT id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until FROM users WHERE LOWER(users.handle) = ANY($1::text[])
+`
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsPremium,
+			&i.Handle,
+			&i.DisplayName,
+			&i.Bio,
+			&i.SuspendedAt,
+			&i.Role,
+			&i.TotpSecret,
+			&i.TotpEnabledAt,
+			&i.TotpLastCounter,
+			&i.EmailVerifiedAt,
+			&i.PendingEmail,
+			&i.FailedLoginCount,
+			&i.LastFailedLoginAt,
+			&i.LockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUser = `-- name: LockUser :exec
+UPDATE users
+SET locked_until = $2
+WHERE users.id = $1
+`
+
+type LockUserParams struct {
+	ID          uuid.UUID
+	LockedUntil sql.NullTime
+}
+
+func (q *Queries) LockUser(ctx context.Context, arg LockUserParams) error {
+	_, err := q.db.ExecContext(ctx, lockUser, arg.ID, arg.LockedUntil)
+	return err
+}
+
+const recordFailedLogin = `-- name: RecordFailedLogin :one
+UPDATE users
+SET
+    failed_login_count = failed_login_count + 1,
+    last_failed_login_at = $2
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type RecordFailedLoginParams struct {
+	ID                uuid.UUID
+	LastFailedLoginAt sql.NullTime
+}
+
+func (q *Queries) RecordFailedLogin(ctx context.Context, arg RecordFailedLoginParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, recordFailedLogin, arg.ID, arg.LastFailedLoginAt)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
 
-const updateUser = `-- name: UpdateUser :one
+const setPendingEmail = `-- name: SetPendingEmail :one
 UPDATE users
-SET 
-    email = $2,
-    hashed_password = $3,
+SET
+    pending_email = $2,
     updated_at = NOW()
-WHERE 
-    users.id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_premium
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type SetPendingEmailParams struct {
+	ID           uuid.UUID
+	PendingEmail sql.NullString
+}
+
+func (q *Queries) SetPendingEmail(ctx context.Context, arg SetPendingEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setPendingEmail, arg.ID, arg.PendingEmail)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const setTOTPSecret = `-- name: SetTOTPSecret :one
+UPDATE users
+SET
+    totp_secret = $2,
+    totp_enabled_at = NULL,
+    totp_last_counter = NULL,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type SetTOTPSecretParams struct {
+	ID         uuid.UUID
+	TotpSecret sql.NullString
+}
+
+func (q *Queries) SetTOTPSecret(ctx context.Context, arg SetTOTPSecretParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setTOTPSecret, arg.ID, arg.TotpSecret)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const setUserRoleByEmail = `-- name: SetUserRoleByEmail :one
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE users.email = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type SetUserRoleByEmailParams struct {
+	Email string
+	Role  string
+}
+
+func (q *Queries) SetUserRoleByEmail(ctx context.Context, arg SetUserRoleByEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRoleByEmail, arg.Email, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET
+    suspended_at = NOW(),
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET
+    hashed_password = $2,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
 `
 
-type UpdateUserParams struct {
+type UpdateUserPasswordParams struct {
 	ID             uuid.UUID
-	Email          string
 	HashedPassword string
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword)
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -144,6 +694,65 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET
+    handle = $2,
+    display_name = $3,
+    bio = $4,
+    updated_at = NOW()
+WHERE
+    users.id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile, arg.ID, arg.Handle, arg.DisplayName, arg.Bio)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
@@ -152,7 +761,7 @@ const upgradeUserToPremium = `-- name: UpgradeUserToPremium :one
 UPDATE users
 SET is_premium = true
 WHERE users.id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_premium
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
 `
 
 func (q *Queries) UpgradeUserToPremium(ctx context.Context, id uuid.UUID) (User, error) {
@@ -165,6 +774,82 @@ func (q *Queries) UpgradeUserToPremium(ctx context.Context, id uuid.UUID) (User,
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const useTOTPCounter = `-- name: UseTOTPCounter :execrows
+UPDATE users
+SET totp_last_counter = $1::bigint
+WHERE
+    users.id = $2
+    AND (totp_last_counter IS NULL OR totp_last_counter < $1::bigint)
+`
+
+type UseTOTPCounterParams struct {
+	Counter int64
+	ID      uuid.UUID
+}
+
+func (q *Queries) UseTOTPCounter(ctx context.Context, arg UseTOTPCounterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useTOTPCounter, arg.Counter, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE users.id = $1
+  AND users.email = $2
+RETURNING id, created_at, updated_at, email, hashed_password, is_premium, handle, display_name, bio, suspended_at, role, totp_secret, totp_enabled_at, totp_last_counter, email_verified_at, pending_email, failed_login_count, last_failed_login_at, locked_until
+`
+
+type VerifyUserEmailParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsPremium,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.TotpSecret,
+		&i.TotpEnabledAt,
+		&i.TotpLastCounter,
+		&i.EmailVerifiedAt,
+		&i.PendingEmail,
+		&i.FailedLoginCount,
+		&i.LastFailedLoginAt,
+		&i.LockedUntil,
 	)
 	return i, err
 }
@@ -0,0 +1,266 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Action is what a filter wants done with a piece of content.
+// Actions are ordered by severity, so the most severe one in a chain wins.
+type Action string
+
+const (
+	ActionAllow  Action = "allow"
+	ActionMask   Action = "mask"
+	ActionHold   Action = "hold"
+	ActionReject Action = "reject"
+)
+
+var severity = map[Action]int{
+	ActionAllow:  0,
+	ActionMask:   1,
+	ActionHold:   2,
+	ActionReject: 3,
+}
+
+// ParseAction validates an action read from config, a word list or a request
+func ParseAction(s string) (Action, error) {
+	a := Action(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := severity[a]; !ok || a == ActionAllow {
+		return "", fmt.Errorf("invalid action %q: must be mask, hold or reject", s)
+	}
+	return a, nil
+}
+
+// Result is the outcome of running content through a filter
+type Result struct {
+	Action  Action
+	Text    string   // the content, with any masked words replaced
+	Matches []string // the list entries that matched, for logging and review
+}
+
+type ContentFilter interface {
+	Filter(text string) Result
+}
+
+// Chain runs content through each filter in turn, feeding masked text forward.
+// It stops early on a rejection.
+type Chain []ContentFilter
+
+func (c Chain) Filter(text string) Result {
+	res := Result{Action: ActionAllow, Text: text}
+	for _, f := range c {
+		r := f.Filter(res.Text)
+		res.Text = r.Text
+		res.Matches = append(res.Matches, r.Matches...)
+		if severity[r.Action] > severity[res.Action] {
+			res.Action = r.Action
+		}
+		if res.Action == ActionReject {
+			break
+		}
+	}
+	return res
+}
+
+// Folder maps a word onto the form word lists are matched against
+type Folder func(string) string
+
+// FoldUnicode lowercases a word, flattens compatibility characters (e.g. fullwidth
+// letters) and strips diacritics, so "ＫÉRFUFFLE" matches "kerfuffle"
+func FoldUnicode(s string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(t, s)
+	if err != nil {
+		folded = s
+	}
+	return strings.ToLower(folded)
+}
+
+var leetReplacer = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"8", "b",
+	"@", "a",
+	"$", "s",
+	"!", "i",
+	"|", "l",
+)
+
+// FoldLeetspeak maps common character substitutions back onto letters, so "k3rfuffl3" matches "kerfuffle"
+func FoldLeetspeak(s string) string {
+	return leetReplacer.Replace(s)
+}
+
+// Word is a word list entry along with what to do when it turns up
+type Word struct {
+	Word   string
+	Action Action
+}
+
+// WordListFilter matches whole words against a word list. Words are split on anything
+// that isn't a letter, digit or leetspeak symbol, and each one is folded before matching.
+// The list can be swapped at any time, so it can be managed without a redeploy.
+type WordListFilter struct {
+	folders []Folder
+
+	mu    sync.RWMutex
+	words map[string]Action
+}
+
+func NewWordListFilter(folders ...Folder) *WordListFilter {
+	return &WordListFilter{
+		folders: folders,
+		words:   map[string]Action{},
+	}
+}
+
+// SetWords replaces the word list
+func (f *WordListFilter) SetWords(words []Word) {
+	folded := make(map[string]Action, len(words))
+	for _, w := range words {
+		key := f.fold(w.Word)
+		// keep the most severe action if two entries fold to the same word
+		if severity[w.Action] > severity[folded[key]] {
+			folded[key] = w.Action
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.words = folded
+}
+
+func (f *WordListFilter) fold(s string) string {
+	for _, fold := range f.folders {
+		s = fold(s)
+	}
+	return s
+}
+
+func (f *WordListFilter) Filter(text string) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	res := Result{Action: ActionAllow}
+	var out strings.Builder
+	for _, tok := range tokenize(text) {
+		if !tok.word {
+			out.WriteString(tok.text)
+			continue
+		}
+
+		// leetspeak symbols at the edges are more likely punctuation ("Kerfuffle!")
+		word := tok.text
+		action, matched := f.match(word)
+		if !matched {
+			word = strings.TrimFunc(tok.text, isLeetSymbol)
+			action, matched = f.match(word)
+		}
+		if !matched {
+			out.WriteString(tok.text)
+			continue
+		}
+
+		res.Matches = append(res.Matches, f.fold(word))
+		if severity[action] > severity[res.Action] {
+			res.Action = action
+		}
+		if action == ActionMask {
+			out.WriteString(mask(tok.text))
+		} else {
+			out.WriteString(tok.text)
+		}
+	}
+	res.Text = out.String()
+	return res
+}
+
+func (f *WordListFilter) match(word string) (Action, bool) {
+	if word == "" {
+		return "", false
+	}
+	action, ok := f.words[f.fold(word)]
+	return action, ok
+}
+
+// mask blurs a word while keeping any punctuation stuck to its edges
+func mask(word string) string {
+	start := strings.IndexFunc(word, func(r rune) bool { return !isLeetSymbol(r) })
+	end := strings.LastIndexFunc(word, func(r rune) bool { return !isLeetSymbol(r) })
+	if start < 0 {
+		return "****"
+	}
+	_, size := utf8.DecodeRuneInString(word[end:])
+	return word[:start] + "****" + word[end+size:]
+}
+
+type token struct {
+	text string
+	word bool
+}
+
+// tokenize splits text into alternating word and non-word runs, losslessly
+func tokenize(text string) []token {
+	var toks []token
+	var cur strings.Builder
+	curWord := false
+	for _, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || isLeetSymbol(r)
+		if cur.Len() > 0 && isWord != curWord {
+			toks = append(toks, token{text: cur.String(), word: curWord})
+			cur.Reset()
+		}
+		curWord = isWord
+		cur.WriteRune(r)
+	}
+	if cur.Len() > 0 {
+		toks = append(toks, token{text: cur.String(), word: curWord})
+	}
+	return toks
+}
+
+func isLeetSymbol(r rune) bool {
+	return strings.ContainsRune("@$!|", r)
+}
+
+// LoadWordsFile reads a word list with one entry per line, as either "word" (masked)
+// or "word action". Blank lines and lines starting with # are skipped.
+func LoadWordsFile(path string) ([]Word, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []Word
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		w := Word{Word: fields[0], Action: ActionMask}
+		if len(fields) > 1 {
+			w.Action, err = ParseAction(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+			}
+		}
+		words = append(words, w)
+	}
+	return words, scanner.Err()
+}
@@ -0,0 +1,209 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFoldUnicode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"kerfuffle", "kerfuffle"},
+		{"KERFUFFLE", "kerfuffle"},
+		{"ＫÉRFUFFLE", "kerfuffle"},
+		{"Crème Brûlée", "creme brulee"},
+	}
+
+	for _, tt := range tests {
+		if got := FoldUnicode(tt.in); got != tt.want {
+			t.Errorf("FoldUnicode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFoldLeetspeak(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"kerfuffle", "kerfuffle"},
+		{"k3rfuffl3", "kerfuffle"},
+		{"p@$$w0rd", "password"},
+		{"7h1$", "this"},
+		{"|4b", "lab"},
+	}
+
+	for _, tt := range tests {
+		if got := FoldLeetspeak(tt.in); got != tt.want {
+			t.Errorf("FoldLeetspeak(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Action
+		wantErr bool
+	}{
+		{"mask", ActionMask, false},
+		{" HOLD ", ActionHold, false},
+		{"Reject", ActionReject, false},
+		{"allow", "", true},
+		{"", "", true},
+		{"ban", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAction(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAction(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAction(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWordListFilter(t *testing.T) {
+	filter := NewWordListFilter(FoldUnicode, FoldLeetspeak)
+	filter.SetWords([]Word{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "sharbert", Action: ActionHold},
+		{Word: "fornax", Action: ActionReject},
+	})
+
+	tests := []struct {
+		name string
+		in   string
+		want Result
+	}{
+		{"clean", "hello world", Result{Action: ActionAllow, Text: "hello world"}},
+		{"masked", "what a kerfuffle", Result{Action: ActionMask, Text: "what a ****", Matches: []string{"kerfuffle"}}},
+		{"punctuation kept", "what a kerfuffle!", Result{Action: ActionMask, Text: "what a ****!", Matches: []string{"kerfuffle"}}},
+		{"leetspeak", "K3RFUFFL3", Result{Action: ActionMask, Text: "****", Matches: []string{"kerfuffle"}}},
+		{"unicode", "ＫÉRFUFFLE", Result{Action: ActionMask, Text: "****", Matches: []string{"kerfuffle"}}},
+		{"whole words only", "kerfuffles", Result{Action: ActionAllow, Text: "kerfuffles"}},
+		{"held", "a sharbert", Result{Action: ActionHold, Text: "a sharbert", Matches: []string{"sharbert"}}},
+		{"rejected", "f0rnax", Result{Action: ActionReject, Text: "f0rnax", Matches: []string{"fornax"}}},
+		{"most severe wins", "kerfuffle sharbert", Result{Action: ActionHold, Text: "**** sharbert", Matches: []string{"kerfuffle", "sharbert"}}},
+	}
+
+	for _, tt := range tests {
+		if got := filter.Filter(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Filter(%q) = %+v, want %+v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWordListFilterKeepsMostSevereDuplicate(t *testing.T) {
+	filter := NewWordListFilter(FoldUnicode, FoldLeetspeak)
+	filter.SetWords([]Word{
+		{Word: "kerfuffle", Action: ActionMask},
+		{Word: "k3rfuffle", Action: ActionReject},
+	})
+
+	if got := filter.Filter("kerfuffle").Action; got != ActionReject {
+		t.Errorf("action = %q, want %q", got, ActionReject)
+	}
+}
+
+// stubFilter always returns the same action, counting how often it runs
+type stubFilter struct {
+	action Action
+	calls  *int
+}
+
+func (f stubFilter) Filter(text string) Result {
+	*f.calls++
+	return Result{Action: f.action, Text: text}
+}
+
+func TestChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		actions   []Action
+		want      Action
+		wantCalls int
+	}{
+		{"empty", nil, ActionAllow, 0},
+		{"all allow", []Action{ActionAllow, ActionAllow}, ActionAllow, 2},
+		{"most severe wins", []Action{ActionHold, ActionMask}, ActionHold, 2},
+		{"stops on reject", []Action{ActionReject, ActionMask}, ActionReject, 1},
+	}
+
+	for _, tt := range tests {
+		calls := 0
+		chain := Chain{}
+		for _, action := range tt.actions {
+			chain = append(chain, stubFilter{action: action, calls: &calls})
+		}
+
+		got := chain.Filter("text")
+		if got.Action != tt.want {
+			t.Errorf("%s: action = %q, want %q", tt.name, got.Action, tt.want)
+		}
+		if calls != tt.wantCalls {
+			t.Errorf("%s: ran %d filters, want %d", tt.name, calls, tt.wantCalls)
+		}
+	}
+}
+
+func TestChainFeedsMaskedTextForward(t *testing.T) {
+	first := NewWordListFilter(FoldUnicode)
+	first.SetWords([]Word{{Word: "kerfuffle", Action: ActionMask}})
+	second := NewWordListFilter(FoldUnicode)
+	second.SetWords([]Word{{Word: "sharbert", Action: ActionMask}})
+
+	got := Chain{first, second}.Filter("kerfuffle and sharbert")
+	want := Result{Action: ActionMask, Text: "**** and ****", Matches: []string{"kerfuffle", "sharbert"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadWordsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Word
+		wantErr bool
+	}{
+		{
+			name:    "actions default to mask",
+			content: "# comment\n\nkerfuffle\nsharbert hold\nfornax reject\n",
+			want: []Word{
+				{Word: "kerfuffle", Action: ActionMask},
+				{Word: "sharbert", Action: ActionHold},
+				{Word: "fornax", Action: ActionReject},
+			},
+		},
+		{
+			name:    "invalid action",
+			content: "kerfuffle ban\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "words.txt")
+		err := os.WriteFile(path, []byte(tt.content), 0o600)
+		if err != nil {
+			t.Fatalf("%s: writing words file: %v", tt.name, err)
+		}
+
+		got, err := LoadWordsFile(path)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testLimit refills one token a second, which keeps the arithmetic exact
+var testLimit = Limit{Requests: 3, Period: 3 * time.Second}
+
+func TestMemoryStoreTake(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name string
+		// offset is when the request is made, from the first request
+		offset time.Duration
+		want   Result
+	}{
+		{"first of burst", 0, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+		{"second of burst", 0, Result{Allowed: true, Remaining: 1, Reset: 2 * time.Second}},
+		{"last of burst", 0, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{"burst used up", 0, Result{Allowed: false, Remaining: 0, Reset: 3 * time.Second, RetryAfter: time.Second}},
+		{"part refilled", 500 * time.Millisecond, Result{Allowed: false, Remaining: 0, Reset: 2500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{"one refilled", time.Second, Result{Allowed: true, Remaining: 0, Reset: 3 * time.Second}},
+		{"refill is capped at the burst", time.Hour, Result{Allowed: true, Remaining: 2, Reset: time.Second}},
+	}
+
+	store := NewMemoryStore()
+	for _, tt := range tests {
+		got, err := store.Take(context.Background(), "key", testLimit, start.Add(tt.offset))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreTakeSeparateBuckets(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+
+	for i := 0; i < testLimit.Requests; i++ {
+		store.Take(context.Background(), "a", testLimit, now)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		limit Limit
+		want  bool
+	}{
+		{"used up key", "a", testLimit, false},
+		{"other key", "b", testLimit, true},
+		{"changed limit starts afresh", "a", Limit{Requests: 5, Period: time.Minute}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := store.Take(context.Background(), tt.key, tt.limit, now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got.Allowed != tt.want {
+			t.Errorf("%s: allowed = %v, want %v", tt.name, got.Allowed, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.Take(context.Background(), "key", testLimit, now)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"still refilling", 500 * time.Millisecond, true},
+		{"refilled", time.Second, false},
+	}
+
+	for _, tt := range tests {
+		store.Sweep(now.Add(tt.offset))
+		_, kept := store.buckets["key"]
+		if kept != tt.want {
+			t.Errorf("%s: kept = %v, want %v", tt.name, kept, tt.want)
+		}
+	}
+}
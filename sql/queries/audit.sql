@@ -0,0 +1,35 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, actor_id, action, target_type, target_id, ip, user_agent, diff)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2, $3, $4, $5, $6, $7
+);
+
+-- name: ListAuditEventsAsc :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR audit_events.actor_id = sqlc.narg('actor_id')::uuid)
+  AND (sqlc.narg('action')::text IS NULL OR audit_events.action = sqlc.narg('action')::text)
+  AND (sqlc.narg('target_type')::text IS NULL OR audit_events.target_type = sqlc.narg('target_type')::text)
+  AND (sqlc.narg('target_id')::text IS NULL OR audit_events.target_id = sqlc.narg('target_id')::text)
+  AND (sqlc.narg('since')::timestamp IS NULL OR audit_events.created_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR audit_events.created_at < sqlc.narg('until')::timestamp)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (audit_events.created_at, audit_events.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY audit_events.created_at ASC, audit_events.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListAuditEventsDesc :many
+SELECT * FROM audit_events
+WHERE (sqlc.narg('actor_id')::uuid IS NULL OR audit_events.actor_id = sqlc.narg('actor_id')::uuid)
+  AND (sqlc.narg('action')::text IS NULL OR audit_events.action = sqlc.narg('action')::text)
+  AND (sqlc.narg('target_type')::text IS NULL OR audit_events.target_type = sqlc.narg('target_type')::text)
+  AND (sqlc.narg('target_id')::text IS NULL OR audit_events.target_id = sqlc.narg('target_id')::text)
+  AND (sqlc.narg('since')::timestamp IS NULL OR audit_events.created_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR audit_events.created_at < sqlc.narg('until')::timestamp)
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (audit_events.created_at, audit_events.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY audit_events.created_at DESC, audit_events.id DESC
+LIMIT sqlc.arg('row_limit');
@@ -1,15 +1,149 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_id, root_id, kind, quote_of_id, moderation_status)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6, $7
 )
 RETURNING *;
 
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, kind, rechirp_of_id)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), '', $1, 'rechirp', $2
+)
+ON CONFLICT (user_id, rechirp_of_id) WHERE rechirp_of_id IS NOT NULL DO NOTHING
+RETURNING *;
+
 -- name: GetChirp :one
 SELECT * FROM chirps WHERE chirps.id = $1;
 
--- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY chirps.created_at ASC;
+-- name: ListChirpsAsc :many
+SELECT * FROM chirps
+WHERE chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+    OR sqlc.arg('include_hidden')::boolean
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsDesc :many
+SELECT * FROM chirps
+WHERE chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+    OR sqlc.arg('include_hidden')::boolean
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsByAuthorAsc :many
+SELECT * FROM chirps
+WHERE chirps.user_id = sqlc.arg('author_id')
+  AND chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+    OR sqlc.arg('include_hidden')::boolean
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsByAuthorDesc :many
+SELECT * FROM chirps
+WHERE chirps.user_id = sqlc.arg('author_id')
+  AND chirps.deleted_at IS NULL
+  AND (
+    chirps.moderation_status = 'visible'
+    OR chirps.user_id = sqlc.narg('viewer_id')::uuid
+    OR sqlc.arg('include_hidden')::boolean
+  )
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps WHERE chirps.id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, parent_id) AS (
+    SELECT c.id, c.parent_id FROM chirps c WHERE c.id = sqlc.arg('chirp_id')
+    UNION ALL
+    SELECT c.id, c.parent_id FROM chirps c JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT chirps.* FROM chirps
+WHERE chirps.id IN (SELECT ancestors.id FROM ancestors)
+  AND chirps.id <> sqlc.arg('chirp_id')
+ORDER BY chirps.created_at ASC, chirps.id ASC;
+
+-- name: ListChirpDescendantsAsc :many
+WITH RECURSIVE descendants(id) AS (
+    SELECT c.id FROM chirps c WHERE c.parent_id = sqlc.arg('chirp_id')::uuid
+    UNION ALL
+    SELECT c.id FROM chirps c JOIN descendants d ON c.parent_id = d.id
+)
+SELECT chirps.* FROM chirps
+WHERE chirps.id IN (SELECT descendants.id FROM descendants)
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpDescendantsDesc :many
+WITH RECURSIVE descendants(id) AS (
+    SELECT c.id FROM chirps c WHERE c.parent_id = sqlc.arg('chirp_id')::uuid
+    UNION ALL
+    SELECT c.id FROM chirps c JOIN descendants d ON c.parent_id = d.id
+)
+SELECT chirps.* FROM chirps
+WHERE chirps.id IN (SELECT descendants.id FROM descendants)
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps WHERE chirps.parent_id = sqlc.arg('chirp_id')::uuid
+);
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET
+    body = '',
+    deleted_at = NOW(),
+    updated_at = NOW()
+WHERE chirps.id = $1;
 
 -- name: DeleteChirp :exec
-DELETE FROM chirps WHERE chirps.id = $1;
\ No newline at end of file
+DELETE FROM chirps WHERE chirps.id = $1;
+
+-- name: SetChirpModerationStatus :one
+UPDATE chirps
+SET
+    moderation_status = $2,
+    updated_at = NOW()
+WHERE chirps.id = $1
+RETURNING *;
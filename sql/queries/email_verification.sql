@@ -0,0 +1,13 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (token_hash, created_at, user_id, email, expires_at)
+VALUES (
+    $1, NOW(), $2, $3, NOW() + INTERVAL '24 hours'
+);
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET used_at = NOW()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > NOW()
+RETURNING user_id, email;
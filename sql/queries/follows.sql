@@ -0,0 +1,83 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follows.follower_id = $1
+  AND follows.followee_id = $2;
+
+-- name: ListFollowersAsc :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (follows.created_at, users.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY follows.created_at ASC, users.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFollowersDesc :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (follows.created_at, users.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFollowingAsc :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (follows.created_at, users.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY follows.created_at ASC, users.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListFollowingDesc :many
+SELECT sqlc.embed(users), follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (follows.created_at, users.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY follows.created_at DESC, users.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListTimelineAsc :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListTimelineDesc :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
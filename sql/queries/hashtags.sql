@@ -0,0 +1,45 @@
+-- name: AddChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, tags.tag, NOW()
+FROM unnest(sqlc.arg('tags')::text[]) AS tags(tag)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags WHERE chirp_hashtags.chirp_id = $1;
+
+-- name: ListChirpsByHashtagAsc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg('tag')
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpsByHashtagDesc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+WHERE chirp_hashtags.tag = sqlc.arg('tag')
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: GetTrendingHashtags :many
+SELECT chirp_hashtags.tag, COUNT(*) AS uses
+FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at > sqlc.arg('since')
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+GROUP BY chirp_hashtags.tag
+ORDER BY uses DESC, chirp_hashtags.tag ASC
+LIMIT sqlc.arg('row_limit');
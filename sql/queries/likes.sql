@@ -0,0 +1,50 @@
+-- name: LikeChirp :execrows
+WITH inserted AS (
+    INSERT INTO likes (user_id, chirp_id, created_at)
+    VALUES (sqlc.arg('user_id'), sqlc.arg('chirp_id'), NOW())
+    ON CONFLICT DO NOTHING
+    RETURNING likes.chirp_id
+)
+UPDATE chirps
+SET like_count = like_count + 1
+WHERE chirps.id IN (SELECT inserted.chirp_id FROM inserted);
+
+-- name: UnlikeChirp :execrows
+WITH deleted AS (
+    DELETE FROM likes
+    WHERE likes.user_id = sqlc.arg('user_id')
+      AND likes.chirp_id = sqlc.arg('chirp_id')
+    RETURNING likes.chirp_id
+)
+UPDATE chirps
+SET like_count = like_count - 1
+WHERE chirps.id IN (SELECT deleted.chirp_id FROM deleted);
+
+-- name: ListLikedChirpIDs :many
+SELECT likes.chirp_id FROM likes
+WHERE likes.user_id = sqlc.arg('user_id')
+  AND likes.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: ListChirpLikersAsc :many
+SELECT sqlc.embed(users), likes.created_at AS liked_at
+FROM likes
+JOIN users ON users.id = likes.user_id
+WHERE likes.chirp_id = sqlc.arg('chirp_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (likes.created_at, users.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY likes.created_at ASC, users.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListChirpLikersDesc :many
+SELECT sqlc.embed(users), likes.created_at AS liked_at
+FROM likes
+JOIN users ON users.id = likes.user_id
+WHERE likes.chirp_id = sqlc.arg('chirp_id')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (likes.created_at, users.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY likes.created_at DESC, users.id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,18 @@
+-- name: GetLoginIPFailures :one
+SELECT * FROM login_ip_failures
+WHERE ip = $1;
+
+-- name: RecordLoginIPFailure :one
+INSERT INTO login_ip_failures (ip, failed_count, last_failed_at)
+VALUES (
+    $1, 1, $2
+)
+ON CONFLICT (ip) DO UPDATE
+SET
+    failed_count = login_ip_failures.failed_count + 1,
+    last_failed_at = EXCLUDED.last_failed_at
+RETURNING *;
+
+-- name: ClearLoginIPFailures :exec
+DELETE FROM login_ip_failures
+WHERE ip = $1;
@@ -0,0 +1,41 @@
+-- name: AddChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, created_at)
+SELECT sqlc.arg('chirp_id')::uuid, mentioned.user_id, NOW()
+FROM unnest(sqlc.arg('user_ids')::uuid[]) AS mentioned(user_id)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_mentions.chirp_id = $1;
+
+-- name: ListChirpMentions :many
+SELECT chirp_mentions.chirp_id, users.id AS user_id, users.handle
+FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY users.handle ASC;
+
+-- name: ListMentionsAsc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListMentionsDesc :many
+SELECT chirps.* FROM chirps
+JOIN chirp_mentions ON chirp_mentions.chirp_id = chirps.id
+WHERE chirp_mentions.user_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
@@ -0,0 +1,16 @@
+-- name: ListModerationWords :many
+SELECT * FROM moderation_words ORDER BY moderation_words.word ASC;
+
+-- name: UpsertModerationWord :one
+INSERT INTO moderation_words (word, action, created_at, updated_at)
+VALUES (
+    $1, $2, NOW(), NOW()
+)
+ON CONFLICT (word) DO UPDATE
+SET
+    action = EXCLUDED.action,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteModerationWord :execrows
+DELETE FROM moderation_words WHERE moderation_words.word = $1;
@@ -0,0 +1,16 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, created_at, user_id, code_hash)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE
+    user_id = $1
+    AND code_hash = $2
+    AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes WHERE user_id = $1;
@@ -1,7 +1,7 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, parent_token, user_agent, ip, last_used_at)
 VALUES (
-    $1, NOW(), NOW(), $2, NOW() + INTERVAL '60 days', $3
+    $1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, $8, NOW()
 )
 RETURNING *;
 
@@ -17,3 +17,71 @@ SET
 WHERE token = $1
   AND revoked_at IS NULL
 RETURNING *;
+
+-- name: RevokeUserTokens :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL;
+
+-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    rotated_at = NOW(),
+    last_used_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.token = $1
+  AND refresh_tokens.revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeTokenFamily :exec
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.family_id = $1
+  AND refresh_tokens.revoked_at IS NULL;
+
+-- name: ListUserSessions :many
+SELECT
+    refresh_tokens.family_id,
+    refresh_tokens.client_id,
+    refresh_tokens.user_agent,
+    refresh_tokens.ip,
+    refresh_tokens.last_used_at,
+    refresh_tokens.expires_at,
+    (
+        SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = refresh_tokens.family_id
+    )::timestamp AS signed_in_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+  AND refresh_tokens.revoked_at IS NULL
+  AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
+
+-- name: RevokeUserSession :execrows
+UPDATE refresh_tokens
+SET
+    revoked_at = NOW(),
+    updated_at = NOW()
+WHERE refresh_tokens.family_id = $1
+  AND refresh_tokens.user_id = $2
+  AND refresh_tokens.revoked_at IS NULL;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, family_id, parent_token, user_agent, ip, last_used_at, client_id, scopes)
+VALUES (
+    $1, NOW(), NOW(), $2, $3, $4, $5, $6, $7, NOW(), $8, $9
+)
+RETURNING *;
+
+-- name: TokenFamilyActive :one
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.family_id = $1
+      AND refresh_tokens.revoked_at IS NULL
+      AND refresh_tokens.expires_at > NOW()
+);
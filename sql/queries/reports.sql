@@ -0,0 +1,39 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, chirp_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+ON CONFLICT (chirp_id, reporter_id) WHERE reporter_id IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: GetReport :one
+SELECT * FROM reports WHERE reports.id = $1;
+
+-- name: ListReportsAsc :many
+SELECT * FROM reports
+WHERE reports.status = sqlc.arg('status')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (reports.created_at, reports.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY reports.created_at ASC, reports.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ListReportsDesc :many
+SELECT * FROM reports
+WHERE reports.status = sqlc.arg('status')
+  AND (
+    sqlc.narg('cursor_created_at')::timestamp IS NULL
+    OR (reports.created_at, reports.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY reports.created_at DESC, reports.id DESC
+LIMIT sqlc.arg('row_limit');
+
+-- name: ResolveChirpReports :exec
+UPDATE reports
+SET
+    status = $2,
+    resolved_at = NOW(),
+    updated_at = NOW()
+WHERE reports.chirp_id = $1
+  AND reports.status = 'open';
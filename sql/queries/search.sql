@@ -0,0 +1,33 @@
+-- name: SearchChirpsAsc :many
+SELECT
+    sqlc.embed(chirps),
+    ts_rank(chirps.body_tsv, websearch_to_tsquery('english', sqlc.arg('query')::text)) AS rank,
+    ts_headline('english', chirps.body, websearch_to_tsquery('english', sqlc.arg('query')::text)) AS headline
+FROM chirps
+WHERE chirps.body_tsv @@ websearch_to_tsquery('english', sqlc.arg('query')::text)
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_rank')::real IS NULL
+    OR (ts_rank(chirps.body_tsv, websearch_to_tsquery('english', sqlc.arg('query')::text)), chirps.created_at, chirps.id)
+      > (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY rank ASC, chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('row_limit');
+
+-- name: SearchChirpsDesc :many
+SELECT
+    sqlc.embed(chirps),
+    ts_rank(chirps.body_tsv, websearch_to_tsquery('english', sqlc.arg('query')::text)) AS rank,
+    ts_headline('english', chirps.body, websearch_to_tsquery('english', sqlc.arg('query')::text)) AS headline
+FROM chirps
+WHERE chirps.body_tsv @@ websearch_to_tsquery('english', sqlc.arg('query')::text)
+  AND chirps.deleted_at IS NULL
+  AND chirps.moderation_status = 'visible'
+  AND (
+    sqlc.narg('cursor_rank')::real IS NULL
+    OR (ts_rank(chirps.body_tsv, websearch_to_tsquery('english', sqlc.arg('query')::text)), chirps.created_at, chirps.id)
+      < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('row_limit');
@@ -1,7 +1,7 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3
 )
 RETURNING *;
 
@@ -17,13 +17,20 @@ DELETE FROM users;
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE users.email = $1;
 
--- name: UpdateUser :one
+-- name: GetUsersByHandles :many
+SELECT * FROM users WHERE LOWER(users.handle) = ANY(sqlc.arg('handles')::text[]);
+
+-- name: GetUserByHandle :one
+SELECT * FROM users WHERE LOWER(users.handle) = LOWER(sqlc.arg('handle')::text);
+
+-- name: UpdateUserProfile :one
 UPDATE users
-SET 
-    email = $2,
-    hashed_password = $3,
+SET
+    handle = $2,
+    display_name = $3,
+    bio = $4,
     updated_at = NOW()
-WHERE 
+WHERE
     users.id = $1
 RETURNING *;
 
@@ -31,4 +38,122 @@ RETURNING *;
 UPDATE users
 SET is_premium = true
 WHERE users.id = $1
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users
+SET
+    suspended_at = NOW(),
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+
+-- name: SetUserRoleByEmail :one
+UPDATE users
+SET
+    role = $2,
+    updated_at = NOW()
+WHERE users.email = $1
+RETURNING *;
+
+-- name: SetTOTPSecret :one
+UPDATE users
+SET
+    totp_secret = $2,
+    totp_enabled_at = NULL,
+    totp_last_counter = NULL,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+
+-- name: EnableTOTP :one
+UPDATE users
+SET
+    totp_enabled_at = NOW(),
+    totp_last_counter = $2,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+
+-- name: DisableTOTP :one
+UPDATE users
+SET
+    totp_secret = NULL,
+    totp_enabled_at = NULL,
+    totp_last_counter = NULL,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+
+-- name: UseTOTPCounter :execrows
+UPDATE users
+SET totp_last_counter = sqlc.arg('counter')::bigint
+WHERE
+    users.id = sqlc.arg('id')
+    AND (totp_last_counter IS NULL OR totp_last_counter < sqlc.arg('counter')::bigint);
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET
+    hashed_password = $2,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+
+-- name: SetPendingEmail :one
+UPDATE users
+SET
+    pending_email = $2,
+    updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE users.id = $1
+  AND users.email = $2
+RETURNING *;
+
+-- name: ConfirmPendingEmail :one
+UPDATE users
+SET
+    email = pending_email,
+    pending_email = NULL,
+    email_verified_at = NOW(),
+    updated_at = NOW()
+WHERE users.id = $1
+  AND users.pending_email = sqlc.arg('email')::text
+RETURNING *;
+
+-- name: RecordFailedLogin :one
+UPDATE users
+SET
+    failed_login_count = failed_login_count + 1,
+    last_failed_login_at = $2
+WHERE users.id = $1
+RETURNING *;
+
+-- name: LockUser :exec
+UPDATE users
+SET locked_until = $2
+WHERE users.id = $1;
+
+-- name: ClearFailedLogins :one
+UPDATE users
+SET
+    failed_login_count = 0,
+    last_failed_login_at = NULL,
+    locked_until = NULL
+WHERE users.id = $1
+RETURNING *;
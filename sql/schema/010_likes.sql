@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE likes (
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE
+);
+
+CREATE INDEX likes_chirp_id_created_at_idx ON likes (chirp_id, created_at);
+
+-- like counts are denormalised onto chirps so listings don't need to aggregate
+ALTER TABLE chirps
+ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN like_count;
+
+DROP TABLE likes;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- rechirps go away with their original, quotes outlive it and render a placeholder instead
+ALTER TABLE chirps
+ADD COLUMN kind TEXT NOT NULL DEFAULT 'chirp' CHECK (kind IN ('chirp', 'rechirp', 'quote')),
+ADD COLUMN rechirp_of_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+ADD COLUMN quote_of_id UUID REFERENCES chirps(id) ON DELETE SET NULL;
+
+-- a user can only rechirp a chirp once
+CREATE UNIQUE INDEX chirps_user_id_rechirp_of_id_idx ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL;
+
+CREATE INDEX chirps_quote_of_id_idx ON chirps (quote_of_id);
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN quote_of_id,
+DROP COLUMN rechirp_of_id,
+DROP COLUMN kind;
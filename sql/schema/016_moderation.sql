@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE moderation_words (
+    word TEXT PRIMARY KEY,
+    action TEXT NOT NULL CHECK (action IN ('mask', 'hold', 'reject')),
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+-- the words replaceProfanity used to hardcode
+INSERT INTO moderation_words (word, action, created_at, updated_at)
+VALUES
+    ('kerfuffle', 'mask', NOW(), NOW()),
+    ('sharbert', 'mask', NOW(), NOW()),
+    ('fornax', 'mask', NOW(), NOW());
+
+-- held chirps are only visible to their author until a moderator gets to them
+ALTER TABLE chirps
+ADD COLUMN moderation_status TEXT NOT NULL DEFAULT 'visible' CHECK (moderation_status IN ('visible', 'held'));
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN moderation_status;
+
+DROP TABLE moderation_words;
@@ -0,0 +1,38 @@
+-- +goose Up
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    -- reports without a reporter were raised by the moderation filters
+    reporter_id UUID,
+    reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'misinformation', 'other', 'flagged')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'dismissed', 'hidden', 'suspended')),
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- a user can only report a chirp once
+CREATE UNIQUE INDEX reports_chirp_reporter_idx ON reports (chirp_id, reporter_id) WHERE reporter_id IS NOT NULL;
+CREATE INDEX reports_status_created_at_idx ON reports (status, created_at, id);
+
+-- moderators can hide chirps from everyone but their author
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_moderation_status_check,
+ADD CONSTRAINT chirps_moderation_status_check CHECK (moderation_status IN ('visible', 'held', 'hidden'));
+
+ALTER TABLE users
+ADD COLUMN suspended_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE users
+DROP COLUMN suspended_at;
+
+UPDATE chirps SET moderation_status = 'held' WHERE moderation_status = 'hidden';
+ALTER TABLE chirps
+DROP CONSTRAINT chirps_moderation_status_check,
+ADD CONSTRAINT chirps_moderation_status_check CHECK (moderation_status IN ('visible', 'held'));
+
+DROP TABLE reports;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- actors and targets aren't foreign keys, so events outlive the users and chirps they mention
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    actor_id UUID,
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    ip TEXT NOT NULL,
+    user_agent TEXT NOT NULL,
+    diff JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at, id);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, created_at, id);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id, created_at, id);
+
+-- the audit log is append-only
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- +goose Down
+DROP TABLE audit_events;
+DROP FUNCTION audit_events_append_only;
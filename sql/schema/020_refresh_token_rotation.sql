@@ -0,0 +1,16 @@
+-- +goose Up
+-- every refresh rotates the token, and a login's chain of tokens shares a family
+ALTER TABLE refresh_tokens
+ADD COLUMN family_id UUID NOT NULL DEFAULT gen_random_uuid(),
+ADD COLUMN parent_token TEXT REFERENCES refresh_tokens(token) ON DELETE SET NULL,
+ADD COLUMN rotated_at TIMESTAMP;
+
+CREATE INDEX refresh_tokens_family_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_family_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN rotated_at,
+DROP COLUMN parent_token,
+DROP COLUMN family_id;
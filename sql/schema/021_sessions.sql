@@ -0,0 +1,16 @@
+-- +goose Up
+-- each token family is a session, described by the device that last used it
+ALTER TABLE refresh_tokens
+ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+ADD COLUMN ip TEXT NOT NULL DEFAULT '',
+ADD COLUMN last_used_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+CREATE INDEX refresh_tokens_user_idx ON refresh_tokens (user_id, revoked_at);
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_idx;
+
+ALTER TABLE refresh_tokens
+DROP COLUMN last_used_at,
+DROP COLUMN ip,
+DROP COLUMN user_agent;
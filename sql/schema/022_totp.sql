@@ -0,0 +1,25 @@
+-- +goose Up
+-- the secret is set when enrolment starts, and only takes effect once confirmed with a first code
+ALTER TABLE users
+ADD COLUMN totp_secret TEXT,
+ADD COLUMN totp_enabled_at TIMESTAMP,
+ADD COLUMN totp_last_counter BIGINT;
+
+-- one-time codes for signing in without the authenticator, stored hashed
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL UNIQUE,
+    used_at TIMESTAMP
+);
+
+CREATE INDEX recovery_codes_user_idx ON recovery_codes (user_id);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+
+ALTER TABLE users
+DROP COLUMN totp_last_counter,
+DROP COLUMN totp_enabled_at,
+DROP COLUMN totp_secret;
@@ -0,0 +1,25 @@
+-- +goose Up
+-- a new email is held as pending until it is confirmed from the new address
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP,
+ADD COLUMN pending_email TEXT;
+
+-- tokens are emailed to the address they verify, and only their hash is kept
+CREATE TABLE email_verification_tokens (
+    token_hash TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    email TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX email_verification_tokens_user_idx ON email_verification_tokens (user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+
+ALTER TABLE users
+DROP COLUMN pending_email,
+DROP COLUMN email_verified_at;
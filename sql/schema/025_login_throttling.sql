@@ -0,0 +1,21 @@
+-- +goose Up
+-- consecutive failed logins, reset by a successful one
+ALTER TABLE users
+ADD COLUMN failed_login_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN last_failed_login_at TIMESTAMP,
+ADD COLUMN locked_until TIMESTAMP;
+
+-- failed logins from each address, whichever accounts they were for
+CREATE TABLE login_ip_failures (
+    ip TEXT PRIMARY KEY,
+    failed_count INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE login_ip_failures;
+
+ALTER TABLE users
+DROP COLUMN locked_until,
+DROP COLUMN last_failed_login_at,
+DROP COLUMN failed_login_count;
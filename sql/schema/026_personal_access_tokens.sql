@@ -0,0 +1,19 @@
+-- +goose Up
+-- long lived tokens for scripts, limited to their scopes - only the hash is kept
+CREATE TABLE personal_access_tokens (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX personal_access_tokens_user_idx ON personal_access_tokens (user_id, revoked_at);
+
+-- +goose Down
+DROP TABLE personal_access_tokens;
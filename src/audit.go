@@ -0,0 +1,245 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// audited actions
+const (
+	auditLoginSucceeded         = "login.succeeded"
+	auditLoginFailed            = "login.failed"
+	auditEmailChangeRequested   = "user.email_change_requested"
+	auditEmailChanged           = "user.email_changed"
+	auditPasswordChanged        = "user.password_changed"
+	auditPasswordResetRequested = "user.password_reset_requested"
+	auditPasswordReset          = "user.password_reset"
+	auditRoleChanged            = "user.role_changed"
+	auditMFAEnabled             = "user.mfa_enabled"
+	auditMFADisabled            = "user.mfa_disabled"
+	auditRecoveryCodeUsed       = "user.recovery_code_used"
+	auditUserLocked             = "user.locked"
+	auditUserUnlocked           = "user.unlocked"
+	auditUserSuspended          = "user.suspended"
+	auditPremiumUpgraded        = "user.premium_upgraded"
+	auditTokenRevoked           = "token.revoked"
+	auditTokenReused            = "token.reuse_detected"
+	auditPATCreated             = "personal_access_token.created"
+	auditPATRevoked             = "personal_access_token.revoked"
+	auditSessionRevoked         = "session.revoked"
+	auditSessionsRevokedAll     = "session.revoked_all"
+	auditOAuthClientCreated     = "oauth_client.created"
+	auditOAuthClientDeleted     = "oauth_client.deleted"
+	auditOAuthAuthorized        = "oauth.authorized"
+	auditChirpDeleted           = "chirp.deleted"
+	auditReportResolved         = "report.resolved"
+	auditAdminReset             = "admin.reset"
+)
+
+// audit target types
+const (
+	auditTargetUser                = "user"
+	auditTargetChirp               = "chirp"
+	auditTargetReport              = "report"
+	auditTargetOAuthClient         = "oauth_client"
+	auditTargetRefreshToken        = "refresh_token"
+	auditTargetPersonalAccessToken = "personal_access_token"
+	auditTargetTokenFamily         = "token_family"
+	auditTargetSystem              = "system"
+)
+
+type AuditEvent struct {
+	ID         uuid.UUID       `json:"id"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ActorID    *uuid.UUID      `json:"actor_id"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	Diff       json.RawMessage `json:"diff"`
+}
+
+// auditEvent is a security relevant action to record. Diffs map each changed field
+// to its old and new values - secrets are only ever recorded as having changed.
+type auditEvent struct {
+	actorID    uuid.UUID // uuid.Nil when the actor is anonymous or unknown
+	action     string
+	targetType string
+	targetID   string
+	diff       map[string]any
+}
+
+// auditChange describes one field of a diff
+type auditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// recordAudit appends an event to the audit log, along with where the request came from.
+// The action it describes has already happened, so a failure is logged rather than returned.
+func (cfg *apiConfig) recordAudit(r *http.Request, event auditEvent) {
+	if event.diff == nil {
+		event.diff = map[string]any{}
+	}
+	diff, err := json.Marshal(event.diff)
+	if err != nil {
+		log.Printf("Failed to encode audit event %s: %s", event.action, err)
+		return
+	}
+
+	err = cfg.db.CreateAuditEvent(r.Context(), database.CreateAuditEventParams{
+		ActorID:    uuid.NullUUID{UUID: event.actorID, Valid: event.actorID != uuid.Nil},
+		Action:     event.action,
+		TargetType: event.targetType,
+		TargetID:   event.targetID,
+		Ip:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		Diff:       diff,
+	})
+	if err != nil {
+		log.Printf("Failed to record audit event %s: %s", event.action, err)
+	}
+}
+
+// clientIP returns the address the request came from, without its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tokenFingerprint identifies a secret token in the audit log without recording the token itself
+func tokenFingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// getAuditEventsHandler - [GET /admin/audit] : serves a page of the audit log, newest first
+func (cfg *apiConfig) getAuditEventsHandler(w http.ResponseWriter, r *http.Request) {
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// optional filters
+	query := r.URL.Query()
+	var actorID uuid.NullUUID
+	if s := query.Get("actor_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid actor ID", err)
+			return
+		}
+		actorID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "since must be an RFC 3339 timestamp", err)
+		return
+	}
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "until must be an RFC 3339 timestamp", err)
+		return
+	}
+	action := optionalString(query.Get("action"))
+	targetType := optionalString(query.Get("target_type"))
+	targetID := optionalString(query.Get("target_id"))
+
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]database.AuditEvent, error) {
+		createdAt, id := cursorArgs(cursor)
+		if forward {
+			return cfg.db.ListAuditEventsDesc(r.Context(), database.ListAuditEventsDescParams{
+				ActorID:         actorID,
+				Action:          action,
+				TargetType:      targetType,
+				TargetID:        targetID,
+				Since:           since,
+				Until:           until,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		}
+		return cfg.db.ListAuditEventsAsc(r.Context(), database.ListAuditEventsAscParams{
+			ActorID:         actorID,
+			Action:          action,
+			TargetType:      targetType,
+			TargetID:        targetID,
+			Since:           since,
+			Until:           until,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+	}
+
+	events, err := paginate(r, pageParams, fetch, func(e database.AuditEvent) pageCursor {
+		return pageCursor{CreatedAt: e.CreatedAt, ID: e.ID}
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get audit events", err)
+		return
+	}
+
+	// map for correct json representation
+	respEvents := page[AuditEvent]{
+		Items: make([]AuditEvent, 0, len(events.Items)),
+		Next:  events.Next,
+		Prev:  events.Prev,
+	}
+	for _, e := range events.Items {
+		respEvents.Items = append(respEvents.Items, mapAuditEvent(e))
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respEvents)
+}
+
+// mapAuditEvent converts a database audit event into its json representation
+func mapAuditEvent(e database.AuditEvent) AuditEvent {
+	event := AuditEvent{
+		ID:         e.ID,
+		CreatedAt:  e.CreatedAt,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.Ip,
+		UserAgent:  e.UserAgent,
+		Diff:       e.Diff,
+	}
+	if e.ActorID.Valid {
+		event.ActorID = &e.ActorID.UUID
+	}
+	return event
+}
+
+// parseTimeParam reads an optional RFC 3339 timestamp from the query string
+func parseTimeParam(s string) (sql.NullTime, error) {
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
+
+// optionalString turns an empty string into a null query argument
+func optionalString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -2,12 +2,21 @@ package main
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strings"
 
 	"github.com/google/uuid"
 	"github.com/wkeebs/chirpy/internal/auth"
 	"github.com/wkeebs/chirpy/internal/database"
+	"github.com/wkeebs/chirpy/internal/moderation"
+)
+
+// chirp kinds - rechirps have no body of their own, quotes embed another chirp alongside theirs
+const (
+	chirpKindChirp   = "chirp"
+	chirpKindRechirp = "rechirp"
+	chirpKindQuote   = "quote"
 )
 
 func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
@@ -24,62 +33,138 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, http.StatusNotFound, "Failed to get Chirp", err)
 		return
 	}
+	if chirp.DeletedAt.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp has been deleted", nil)
+		return
+	}
+	if !cfg.canSeeChirp(r, chirp) {
+		respondWithError(w, http.StatusNotFound, "Failed to get Chirp", nil)
+		return
+	}
+
+	// map for correct json representation
+	respChirps, err := cfg.mapChirps(r, []database.Chirp{chirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get likes", err)
+		return
+	}
 
 	// write response
-	respondWithJSON(w, http.StatusOK, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	respondWithJSON(w, http.StatusOK, respChirps[0])
 }
 
 func (cfg *apiConfig) getAllChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	chirps, err := cfg.db.GetAllChirps(r.Context())
+	pageParams, err := parsePageParams(r)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to get Chirps", err)
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
 		return
 	}
 
-	// map for correct json representation
-	var respChirps []Chirp
-	for _, c := range chirps {
-		respChirps = append(respChirps, Chirp{
-			ID:        c.ID,
-			CreatedAt: c.CreatedAt,
-			UpdatedAt: c.UpdatedAt,
-			Body:      c.Body,
-			UserID:    c.UserID,
-		})
+	// optional author filter
+	var authorID uuid.UUID
+	if s := r.URL.Query().Get("author_id"); s != "" {
+		authorID, err = uuid.Parse(s)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid author ID", err)
+			return
+		}
 	}
 
-	// write response
-	respondWithJSON(w, http.StatusOK, respChirps)
-}
+	// chirps are listed oldest first unless asked otherwise
+	sortAsc := true
+	switch r.URL.Query().Get("sort") {
+	case "", "asc":
+	case "desc":
+		sortAsc = false
+	default:
+		respondWithError(w, http.StatusBadRequest, "sort must be asc or desc", nil)
+		return
+	}
 
-func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Body string `json:"body"`
+	// authors can see their own held and hidden chirps, and moderators can see everything
+	var viewerID uuid.NullUUID
+	if id := cfg.viewerID(r); id != uuid.Nil {
+		viewerID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	includeHidden := cfg.viewerHasRole(r, auth.RoleModerator)
+
+	// reading forward through an ascending listing (or backward through a descending one) means ascending order
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(cursor)
+		ascending := forward == sortAsc
+		switch {
+		case authorID != uuid.Nil && ascending:
+			return cfg.db.ListChirpsByAuthorAsc(r.Context(), database.ListChirpsByAuthorAscParams{
+				ViewerID:        viewerID,
+				IncludeHidden:   includeHidden,
+				AuthorID:        authorID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		case authorID != uuid.Nil:
+			return cfg.db.ListChirpsByAuthorDesc(r.Context(), database.ListChirpsByAuthorDescParams{
+				ViewerID:        viewerID,
+				IncludeHidden:   includeHidden,
+				AuthorID:        authorID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		case ascending:
+			return cfg.db.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+				ViewerID:        viewerID,
+				IncludeHidden:   includeHidden,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		default:
+			return cfg.db.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+				ViewerID:        viewerID,
+				IncludeHidden:   includeHidden,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		}
 	}
 
-	// get JWT from headers
-	token, err := auth.GetBearerToken(r.Header)
+	chirps, err := paginate(r, pageParams, fetch, chirpCursor)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get Chirps", err)
 		return
 	}
 
-	// unpack user ID
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	// map for correct json representation
+	items, err := cfg.mapChirps(r, chirps.Items)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't validate JWT", err)
+		respondWithError(w, http.StatusInternalServerError, "Failed to get likes", err)
 		return
 	}
+	respChirps := page[Chirp]{
+		Items: items,
+		Next:  chirps.Next,
+		Prev:  chirps.Prev,
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respChirps)
+}
+
+func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
+	}
+
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err := decoder.Decode(&params)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
 		return
@@ -102,63 +187,262 @@ func (cfg *apiConfig) createChirpHandler(w http.ResponseWriter, r *http.Request)
 		respondWithJSON(w, http.StatusNotFound, "User not found")
 		return
 	}
+	if cfg.verifiedOnly[actionPost] && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Email address is not verified", nil)
+		return
+	}
+
+	// replies hang off their parent and share its thread's root
+	var parentID, rootID uuid.NullUUID
+	if params.InReplyTo != nil {
+		parent, err := cfg.originalChirp(r, *params.InReplyTo)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Chirp being replied to does not exist", err)
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		rootID = parent.RootID
+		if !rootID.Valid {
+			rootID = parentID
+		}
+	}
+
+	// quotes embed another chirp alongside their own body
+	kind := chirpKindChirp
+	var quoteOfID uuid.NullUUID
+	if params.QuoteOf != nil {
+		if params.Body == "" {
+			respondWithError(w, http.StatusBadRequest, "Quotes must have a body", nil)
+			return
+		}
+		quoted, err := cfg.originalChirp(r, *params.QuoteOf)
+		if err != nil {
+			respondWithError(w, http.StatusNotFound, "Chirp being quoted does not exist", err)
+			return
+		}
+		kind = chirpKindQuote
+		quoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+	}
 
-	cleanedBody := replaceProfanity(params.Body)
+	// run the body through the moderation filters
+	status := moderationStatusVisible
+	result := cfg.moderation.check(params.Body)
+	switch result.Action {
+	case moderation.ActionReject:
+		respondWithError(w, http.StatusUnprocessableEntity, "Chirp contains content that isn't allowed", nil)
+		return
+	case moderation.ActionHold:
+		status = moderationStatusHeld
+		log.Printf("Holding chirp by user %s for review, matched: %s", userID, strings.Join(result.Matches, ", "))
+	}
 
 	// add to database
 	chirp, err := cfg.db.CreateChirp(r.Context(), database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: userID,
+		Body:             result.Text,
+		UserID:           userID,
+		ParentID:         parentID,
+		RootID:           rootID,
+		Kind:             kind,
+		QuoteOfID:        quoteOfID,
+		ModerationStatus: status,
 	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create Chirp", err)
+		return
+	}
+
+	// held chirps stay out of hashtag listings, trending and mention feeds until they are
+	// released, and go into the moderation queue instead
+	if chirp.ModerationStatus == moderationStatusHeld {
+		_, err = cfg.db.CreateReport(r.Context(), database.CreateReportParams{
+			ChirpID: chirp.ID,
+			Reason:  reportReasonFlagged,
+			Details: "matched: " + strings.Join(result.Matches, ", "),
+		})
+		if err != nil {
+			log.Printf("Failed to queue held chirp %s for review: %s", chirp.ID, err)
+		}
+	} else {
+		cfg.indexChirp(r, chirp)
+	}
+
+	// map for correct json representation
+	respChirps, err := cfg.mapChirps(r, []database.Chirp{chirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to map Chirp", err)
+		return
+	}
 
 	// create response
-	respondWithJSON(w, http.StatusCreated, Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
-	})
+	respondWithJSON(w, http.StatusCreated, respChirps[0])
 }
 
-func replaceProfanity(s string) string {
-	const blur string = "****"
-	profaneWords := []string{
-		"kerfuffle",
-		"sharbert",
-		"fornax",
+// indexChirp records a chirp's hashtags and mentions - the chirp stands even if this fails
+func (cfg *apiConfig) indexChirp(r *http.Request, chirp database.Chirp) {
+	if tags := extractHashtags(chirp.Body); len(tags) > 0 {
+		err := cfg.db.AddChirpHashtags(r.Context(), database.AddChirpHashtagsParams{
+			ChirpID: chirp.ID,
+			Tags:    tags,
+		})
+		if err != nil {
+			log.Printf("Failed to index hashtags for chirp %s: %s", chirp.ID, err)
+		}
+	}
+
+	err := cfg.recordMentions(r, chirp)
+	if err != nil {
+		log.Printf("Failed to record mentions for chirp %s: %s", chirp.ID, err)
+	}
+}
+
+// mapChirp converts a database chirp into its json representation
+func mapChirp(c database.Chirp) Chirp {
+	chirp := Chirp{
+		ID:        c.ID,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+		Body:      c.Body,
+		UserID:    c.UserID,
+		Deleted:   c.DeletedAt.Valid,
+		Held:      c.ModerationStatus == moderationStatusHeld,
+		Hidden:    c.ModerationStatus == moderationStatusHidden,
+		LikeCount: c.LikeCount,
+		Kind:      c.Kind,
+		Mentions:  []Mention{},
+	}
+	if c.ParentID.Valid {
+		chirp.InReplyTo = &c.ParentID.UUID
+	}
+	if c.RootID.Valid {
+		chirp.RootID = &c.RootID.UUID
+	}
+	return chirp
+}
+
+// viewerID returns the user making the request, or uuid.Nil for anonymous requests
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	token, ok := cfg.viewer(r)
+	if !ok {
+		return uuid.Nil
+	}
+	return token.UserID
+}
+
+// mapChirps converts database chirps into their json representation. It embeds the
+// originals of rechirps and quotes, lists mentions, and fills in liked_by_me for the viewer.
+func (cfg *apiConfig) mapChirps(r *http.Request, chirps []database.Chirp) ([]Chirp, error) {
+	respChirps := make([]Chirp, 0, len(chirps))
+	for _, c := range chirps {
+		respChirps = append(respChirps, mapChirp(c))
 	}
 
-	splitString := strings.Split(s, " ")
-	for i, word := range splitString {
-		for _, pWord := range profaneWords {
-			if strings.ToLower(word) == pWord {
-				splitString[i] = blur
+	// load the originals of rechirps and quotes in one go
+	var refIDs []uuid.UUID
+	for _, c := range chirps {
+		if c.RechirpOfID.Valid {
+			refIDs = append(refIDs, c.RechirpOfID.UUID)
+		}
+		if c.QuoteOfID.Valid {
+			refIDs = append(refIDs, c.QuoteOfID.UUID)
+		}
+	}
+	refs := make(map[uuid.UUID]*Chirp, len(refIDs))
+	if len(refIDs) > 0 {
+		refChirps, err := cfg.db.GetChirpsByIDs(r.Context(), refIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range refChirps {
+			// tombstoned and held originals are as good as gone
+			if c.DeletedAt.Valid || c.ModerationStatus != moderationStatusVisible {
+				continue
 			}
+			ref := mapChirp(c)
+			refs[c.ID] = &ref
+		}
+	}
+	for i, c := range chirps {
+		switch c.Kind {
+		case chirpKindRechirp:
+			respChirps[i].RechirpOf = newChirpRef(refs, c.RechirpOfID)
+		case chirpKindQuote:
+			respChirps[i].QuoteOf = newChirpRef(refs, c.QuoteOfID)
 		}
 	}
 
-	return strings.Join(splitString, " ")
-}
+	if len(chirps) == 0 {
+		return respChirps, nil
+	}
 
-func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
-	// expects:
-	// 1. an access token in the header
-	// 2. the ID of the chirp to delete in the path
+	// every chirp in the batch, embedded chirps included
+	chirpIDs := make([]uuid.UUID, 0, len(chirps)+len(refs))
+	byID := make(map[uuid.UUID][]*Chirp, len(chirps)+len(refs))
+	for i := range respChirps {
+		chirpIDs = append(chirpIDs, respChirps[i].ID)
+		byID[respChirps[i].ID] = append(byID[respChirps[i].ID], &respChirps[i])
+	}
+	for id, ref := range refs {
+		chirpIDs = append(chirpIDs, id)
+		byID[id] = append(byID[id], ref)
+	}
 
-	// check access token
-	token, err := auth.GetBearerToken(r.Header)
+	// look up mentions for the whole batch
+	mentions, err := cfg.db.ListChirpMentions(r.Context(), chirpIDs)
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find access token", err)
-		return
+		return nil, err
+	}
+	for _, m := range mentions {
+		for _, c := range byID[m.ChirpID] {
+			c.Mentions = append(c.Mentions, Mention{UserID: m.UserID, Handle: m.Handle.String})
+		}
+	}
+
+	viewerID := cfg.viewerID(r)
+	if viewerID == uuid.Nil {
+		return respChirps, nil
 	}
 
-	// unpack user ID
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	// look up the viewer's likes for the whole batch
+	likedIDs, err := cfg.db.ListLikedChirpIDs(r.Context(), database.ListLikedChirpIDsParams{
+		UserID:   viewerID,
+		ChirpIds: chirpIDs,
+	})
 	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
-		return
+		return nil, err
+	}
+
+	liked := make(map[uuid.UUID]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+	for id, cs := range byID {
+		for _, c := range cs {
+			c.LikedByMe = liked[id]
+		}
+	}
+	return respChirps, nil
+}
+
+// newChirpRef embeds an original chirp, falling back to a placeholder when it is gone
+func newChirpRef(refs map[uuid.UUID]*Chirp, id uuid.NullUUID) *ChirpRef {
+	if ref, ok := refs[id.UUID]; ok && id.Valid {
+		return &ChirpRef{Chirp: ref}
 	}
+	return &ChirpRef{Unavailable: true, Message: "chirp unavailable"}
+}
+
+// chirpCursor is the pagination key of a chirp
+func chirpCursor(c database.Chirp) pageCursor {
+	return pageCursor{CreatedAt: c.CreatedAt, ID: c.ID}
+}
+
+func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
+	// expects:
+	// 1. an access token, or a personal access token with the chirps:write scope, in the header
+	// 2. the ID of the chirp to delete in the path
+
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
 
 	// unpack chirp id
 	chirpId, err := uuid.Parse(r.PathValue("chirpID"))
@@ -169,7 +453,7 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 
 	// check that the chirp exists and is authored by the user
 	storedChirp, err := cfg.db.GetChirp(r.Context(), chirpId)
-	if err != nil {
+	if err != nil || storedChirp.DeletedAt.Valid {
 		respondWithError(w, http.StatusNotFound, "Chirp does not exist", err)
 		return
 	}
@@ -178,15 +462,49 @@ func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// delete the chirp
-	cfg.db.DeleteChirp(r.Context(), chirpId)
-
-	// verify it has been deleted
-	_, err = cfg.db.GetChirp(r.Context(), chirpId)
-	if err == nil {
-		respondWithError(w, http.StatusInternalServerError, "Chirp was not deleted correctly", err)
+	// chirps with replies are tombstoned rather than deleted, so their threads stay intact
+	hasReplies, err := cfg.db.ChirpHasReplies(r.Context(), chirpId)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to look up replies", err)
 		return
 	}
+	if hasReplies {
+		err = cfg.db.TombstoneChirp(r.Context(), chirpId)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Chirp was not deleted correctly", err)
+			return
+		}
+
+		// tombstones have no body, so no hashtags or mentions either
+		err = cfg.db.DeleteChirpHashtags(r.Context(), chirpId)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Chirp was not deleted correctly", err)
+			return
+		}
+		err = cfg.db.DeleteChirpMentions(r.Context(), chirpId)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Chirp was not deleted correctly", err)
+			return
+		}
+	} else {
+		// delete the chirp
+		cfg.db.DeleteChirp(r.Context(), chirpId)
+
+		// verify it has been deleted
+		_, err = cfg.db.GetChirp(r.Context(), chirpId)
+		if err == nil {
+			respondWithError(w, http.StatusInternalServerError, "Chirp was not deleted correctly", err)
+			return
+		}
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditChirpDeleted,
+		targetType: auditTargetChirp,
+		targetID:   chirpId.String(),
+		diff:       map[string]any{"body": auditChange{From: storedChirp.Body, To: nil}, "tombstoned": hasReplies},
+	})
 
 	// success - respond with 204
 	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
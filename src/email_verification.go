@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+	"github.com/wkeebs/chirpy/internal/mail"
+)
+
+// actions that REQUIRE_VERIFIED_EMAIL can restrict to users with a verified email
+const (
+	actionPost    = "post"
+	actionRechirp = "rechirp"
+	actionLike    = "like"
+	actionFollow  = "follow"
+	actionReport  = "report"
+)
+
+var verifiableActions = []string{actionPost, actionRechirp, actionLike, actionFollow, actionReport}
+
+// parseVerifiedEmailActions reads a comma separated list of actions, or "all"
+func parseVerifiedEmailActions(s string) (map[string]bool, error) {
+	actions := map[string]bool{}
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		switch {
+		case name == "":
+			continue
+		case name == "all":
+			for _, action := range verifiableActions {
+				actions[action] = true
+			}
+		case slices.Contains(verifiableActions, name):
+			actions[name] = true
+		default:
+			return nil, fmt.Errorf("unknown action %q: must be one of %s, or all", name, strings.Join(verifiableActions, ", "))
+		}
+	}
+	return actions, nil
+}
+
+// validateEmail checks an email is a bare address, without a display name
+func validateEmail(email string) error {
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("email must be a valid address")
+	}
+	return nil
+}
+
+// requireVerifiedEmail turns the user away with a 403 if the action is configured to need a verified email
+// and theirs isn't. It reports whether the handler can go ahead.
+func (cfg *apiConfig) requireVerifiedEmail(w http.ResponseWriter, r *http.Request, userID uuid.UUID, action string) bool {
+	if !cfg.verifiedOnly[action] {
+		return true
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to find user", err)
+		return false
+	}
+	if !user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Email address is not verified", nil)
+		return false
+	}
+	return true
+}
+
+// sendVerificationEmail mails a one-time token confirming that the user owns the given address
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, userID uuid.UUID, email string) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    userID,
+		Email:     email,
+	})
+	if err != nil {
+		return err
+	}
+
+	return cfg.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("To confirm this is your email address, use this token within the next 24 hours:\n\n%s\n\n"+
+			"If you didn't sign up to Chirpy or change your email, you can ignore this email.\n", token),
+	})
+}
+
+// sendVerificationEmailInBackground sends a verification email without holding up the response, logging failures
+func (cfg *apiConfig) sendVerificationEmailInBackground(r *http.Request, userID uuid.UUID, email string) {
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		err := cfg.sendVerificationEmail(ctx, userID, email)
+		if err != nil {
+			log.Printf("Failed to send verification email to user %s: %s", userID, err)
+		}
+	}()
+}
+
+// sendEmailChangeNoticeInBackground tells the old address that a change to a new one was asked for,
+// so the owner can act if it wasn't them
+func (cfg *apiConfig) sendEmailChangeNoticeInBackground(r *http.Request, oldEmail, newEmail string) {
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		err := cfg.mailer.Send(ctx, mail.Message{
+			To:      oldEmail,
+			Subject: "Your Chirpy email address is being changed",
+			Body: fmt.Sprintf("Someone asked to change the email address of your Chirpy account to %s. "+
+				"It will change once the new address is confirmed.\n\n"+
+				"If it wasn't you, reset your password straight away.\n", newEmail),
+		})
+		if err != nil {
+			log.Printf("Failed to send email change notice: %s", err)
+		}
+	}()
+}
+
+// requestEmailVerificationHandler - [POST /api/email-verification/request] : resends the verification email for the user's current address
+func (cfg *apiConfig) requestEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, http.StatusConflict, "Email is already verified", nil)
+		return
+	}
+
+	err = cfg.sendVerificationEmail(r.Context(), user.ID, user.Email)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to send verification email", err)
+		return
+	}
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// confirmEmailVerificationHandler - [POST /api/email-verification/confirm] : verifies an address with an emailed token,
+// completing an email change if it was sent to a pending address
+func (cfg *apiConfig) confirmEmailVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// using the token marks it used, so it can only ever work once
+	verified, err := cfg.db.UseEmailVerificationToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check verification token", err)
+		return
+	}
+
+	// the token either verifies the current address...
+	user, err := cfg.db.VerifyUserEmail(r.Context(), database.VerifyUserEmailParams{
+		ID:    verified.UserID,
+		Email: verified.Email,
+	})
+	if err == nil {
+		respondWithJSON(w, http.StatusOK, mapUser(user))
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusInternalServerError, "Failed to verify email", err)
+		return
+	}
+
+	// ...or confirms a change to the pending one, as long as it hasn't been replaced since
+	oldUser, err := cfg.db.GetUserByID(r.Context(), verified.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+	user, err = cfg.db.ConfirmPendingEmail(r.Context(), database.ConfirmPendingEmailParams{
+		ID:    verified.UserID,
+		Email: verified.Email,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired verification token", err)
+		return
+	}
+	if isUniqueViolation(err, "users_email_key") {
+		respondWithError(w, http.StatusConflict, "Email is already taken", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to change email", err)
+		return
+	}
+
+	// reset emails already sent to the old address shouldn't outlive it
+	err = cfg.db.ExpireUserPasswordResetTokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Failed to expire password reset tokens for user %s: %s", user.ID, err)
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    user.ID,
+		action:     auditEmailChanged,
+		targetType: auditTargetUser,
+		targetID:   user.ID.String(),
+		diff:       map[string]any{"email": auditChange{From: oldUser.Email, To: user.Email}},
+	})
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapUser(user))
+}
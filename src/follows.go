@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// followEntry is a user on either side of a follow, along with when the follow happened
+type followEntry struct {
+	user       database.User
+	followedAt time.Time
+}
+
+func followCursor(f followEntry) pageCursor {
+	return pageCursor{CreatedAt: f.followedAt, ID: f.user.ID}
+}
+
+// followUserHandler - [POST /api/users/{userID}/follow] : follows another user
+func (cfg *apiConfig) followUserHandler(w http.ResponseWriter, r *http.Request) {
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+	if !cfg.requireVerifiedEmail(w, r, userID, actionFollow) {
+		return
+	}
+
+	// unpack the followee's ID
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+	if followeeID == userID {
+		respondWithError(w, http.StatusBadRequest, "Users cannot follow themselves", nil)
+		return
+	}
+
+	// check the followee exists
+	_, err = cfg.db.GetUserByID(r.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+
+	// following twice is a no-op
+	err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to follow user", err)
+		return
+	}
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unfollowUserHandler - [DELETE /api/users/{userID}/follow] : unfollows another user
+func (cfg *apiConfig) unfollowUserHandler(w http.ResponseWriter, r *http.Request) {
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+
+	// unpack the followee's ID
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	// unfollowing someone you don't follow is a no-op
+	err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: userID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to unfollow user", err)
+		return
+	}
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getFollowersHandler - [GET /api/users/{userID}/followers] : lists a user's followers, newest first
+func (cfg *apiConfig) getFollowersHandler(w http.ResponseWriter, r *http.Request) {
+	fetch := func(userID uuid.UUID, forward bool, cursor *pageCursor, limit int32) ([]followEntry, error) {
+		createdAt, id := cursorArgs(cursor)
+		var entries []followEntry
+		if forward {
+			rows, err := cfg.db.ListFollowersDesc(r.Context(), database.ListFollowersDescParams{
+				UserID:          userID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+			for _, row := range rows {
+				entries = append(entries, followEntry{user: row.User, followedAt: row.FollowedAt})
+			}
+			return entries, err
+		}
+		rows, err := cfg.db.ListFollowersAsc(r.Context(), database.ListFollowersAscParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+		for _, row := range rows {
+			entries = append(entries, followEntry{user: row.User, followedAt: row.FollowedAt})
+		}
+		return entries, err
+	}
+
+	cfg.respondWithFollowPage(w, r, fetch)
+}
+
+// getFollowingHandler - [GET /api/users/{userID}/following] : lists the users a user follows, newest first
+func (cfg *apiConfig) getFollowingHandler(w http.ResponseWriter, r *http.Request) {
+	fetch := func(userID uuid.UUID, forward bool, cursor *pageCursor, limit int32) ([]followEntry, error) {
+		createdAt, id := cursorArgs(cursor)
+		var entries []followEntry
+		if forward {
+			rows, err := cfg.db.ListFollowingDesc(r.Context(), database.ListFollowingDescParams{
+				UserID:          userID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+			for _, row := range rows {
+				entries = append(entries, followEntry{user: row.User, followedAt: row.FollowedAt})
+			}
+			return entries, err
+		}
+		rows, err := cfg.db.ListFollowingAsc(r.Context(), database.ListFollowingAscParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+		for _, row := range rows {
+			entries = append(entries, followEntry{user: row.User, followedAt: row.FollowedAt})
+		}
+		return entries, err
+	}
+
+	cfg.respondWithFollowPage(w, r, fetch)
+}
+
+// respondWithFollowPage writes a page of the follow list of the user in the request path
+func (cfg *apiConfig) respondWithFollowPage(
+	w http.ResponseWriter,
+	r *http.Request,
+	fetch func(userID uuid.UUID, forward bool, cursor *pageCursor, limit int32) ([]followEntry, error),
+) {
+	// unpack user ID
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// check the user exists
+	_, err = cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+
+	entries, err := paginate(r, pageParams, func(forward bool, cursor *pageCursor, limit int32) ([]followEntry, error) {
+		return fetch(userID, forward, cursor, limit)
+	}, followCursor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get users", err)
+		return
+	}
+
+	// map for correct json representation
+	respUsers := page[Profile]{
+		Items: make([]Profile, 0, len(entries.Items)),
+		Next:  entries.Next,
+		Prev:  entries.Prev,
+	}
+	for _, e := range entries.Items {
+		respUsers.Items = append(respUsers.Items, mapProfile(e.user))
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respUsers)
+}
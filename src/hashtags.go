@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+const maxHashtagLength = 64
+
+// a hashtag is a # followed by letters, digits or underscores, and can't be glued onto a preceding word
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+// extractHashtags returns the distinct, lowercased hashtags in a chirp body
+func extractHashtags(body string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, match := range hashtagPattern.FindAllStringSubmatch(body, -1) {
+		tag := strings.ToLower(match[1])
+		if len(tag) > maxHashtagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// getHashtagChirpsHandler - [GET /api/hashtags/{tag}/chirps] : serves chirps using a hashtag, newest first
+func (cfg *apiConfig) getHashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	// tags are stored lowercased and without the #
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid hashtag", nil)
+		return
+	}
+
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// reading forward means descending order
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(cursor)
+		if forward {
+			return cfg.db.ListChirpsByHashtagDesc(r.Context(), database.ListChirpsByHashtagDescParams{
+				Tag:             tag,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		}
+		return cfg.db.ListChirpsByHashtagAsc(r.Context(), database.ListChirpsByHashtagAscParams{
+			Tag:             tag,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+	}
+
+	chirps, err := paginate(r, pageParams, fetch, chirpCursor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get Chirps", err)
+		return
+	}
+
+	// map for correct json representation
+	items, err := cfg.mapChirps(r, chirps.Items)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to map Chirps", err)
+		return
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, page[Chirp]{
+		Items: items,
+		Next:  chirps.Next,
+		Prev:  chirps.Prev,
+	})
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"net/http"
+)
+
+// jwksMaxAge is how long other services may cache the key set. A new key has to be
+// published for at least this long before it becomes the active one.
+const jwksMaxAge = "300"
+
+// jwksHandler - [GET /.well-known/jwks.json] : serves the public keys access tokens can be verified with
+func (cfg *apiConfig) jwksHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age="+jwksMaxAge)
+	respondWithJSON(w, http.StatusOK, cfg.jwtKeys.JWKS())
+}
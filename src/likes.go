@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// likeEntry is a user who liked a chirp, along with when they liked it
+type likeEntry struct {
+	user    database.User
+	likedAt time.Time
+}
+
+func likeCursor(l likeEntry) pageCursor {
+	return pageCursor{CreatedAt: l.likedAt, ID: l.user.ID}
+}
+
+// likeChirpHandler - [POST /api/chirps/{chirpID}/like] : likes a chirp
+func (cfg *apiConfig) likeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+	if !cfg.requireVerifiedEmail(w, r, userID, actionLike) {
+		return
+	}
+
+	// unpack chirp id
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
+	}
+
+	// check the chirp exists
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid || chirp.ModerationStatus != moderationStatusVisible {
+		respondWithError(w, http.StatusNotFound, "Chirp does not exist", err)
+		return
+	}
+
+	// liking twice is a no-op, and the counter only moves when the like is new
+	_, err = cfg.db.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to like Chirp", err)
+		return
+	}
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unlikeChirpHandler - [DELETE /api/chirps/{chirpID}/like] : removes a like from a chirp
+func (cfg *apiConfig) unlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+
+	// unpack chirp id
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
+	}
+
+	// unliking a chirp you haven't liked is a no-op
+	_, err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirpID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlike Chirp", err)
+		return
+	}
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getLikersHandler - [GET /api/chirps/{chirpID}/likers] : lists the users who liked a chirp, newest first
+func (cfg *apiConfig) getLikersHandler(w http.ResponseWriter, r *http.Request) {
+	// unpack chirp id
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
+	}
+
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// check the chirp exists and can be seen - likers of a hidden chirp are as private as the chirp
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid || !cfg.canSeeChirp(r, chirp) {
+		respondWithError(w, http.StatusNotFound, "Chirp does not exist", err)
+		return
+	}
+
+	// likers are listed newest first, so reading forward means descending order
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]likeEntry, error) {
+		createdAt, id := cursorArgs(cursor)
+		var entries []likeEntry
+		if forward {
+			rows, err := cfg.db.ListChirpLikersDesc(r.Context(), database.ListChirpLikersDescParams{
+				ChirpID:         chirpID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+			for _, row := range rows {
+				entries = append(entries, likeEntry{user: row.User, likedAt: row.LikedAt})
+			}
+			return entries, err
+		}
+		rows, err := cfg.db.ListChirpLikersAsc(r.Context(), database.ListChirpLikersAscParams{
+			ChirpID:         chirpID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+		for _, row := range rows {
+			entries = append(entries, likeEntry{user: row.User, likedAt: row.LikedAt})
+		}
+		return entries, err
+	}
+
+	entries, err := paginate(r, pageParams, fetch, likeCursor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get likers", err)
+		return
+	}
+
+	// map for correct json representation
+	respUsers := page[Profile]{
+		Items: make([]Profile, 0, len(entries.Items)),
+		Next:  entries.Next,
+		Prev:  entries.Prev,
+	}
+	for _, e := range entries.Items {
+		respUsers.Items = append(respUsers.Items, mapProfile(e.user))
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respUsers)
+}
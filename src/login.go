@@ -2,25 +2,28 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/wkeebs/chirpy/internal/auth"
 	"github.com/wkeebs/chirpy/internal/database"
 )
 
-// refresh tokens expire after 60 days
-// const refreshTokenExpiryTime time.Duration = time.Duration(time.Hour) * 24 * 60
+// refresh tokens expire 60 days after the login that started their family - replacements keep the same expiry,
+// so a session can't be kept alive forever by refreshing it
+const refreshTokenExpiryTime time.Duration = time.Duration(time.Hour) * 24 * 60
 
 func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
 	}
-	type response struct {
-		User
-		Token        string `json:"token"`
-		RefreshToken string `json:"refresh_token"`
+	type mfaResponse struct {
+		MFARequired bool   `json:"mfa_required"`
+		MFAToken    string `json:"mfa_token"`
 	}
 
 	// decode payload
@@ -32,27 +35,139 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// addresses that keep failing have to slow down, whichever accounts they try
+	wait, err := cfg.ipRetryAfter(r)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check login attempts", err)
+		return
+	}
+	if wait > 0 {
+		respondTooManyLogins(w, wait)
+		return
+	}
+
 	// user lookup
 	user, err := cfg.db.GetUserByEmail(r.Context(), params.Email)
 	if err != nil {
+		// check a password anyway, so unknown emails take as long as known ones
+		auth.CheckPasswordHash(params.Password, dummyPasswordHash())
+		cfg.recordIPFailure(r)
+		cfg.recordAudit(r, auditEvent{
+			action:     auditLoginFailed,
+			targetType: auditTargetUser,
+			diff:       map[string]any{"email": params.Email, "reason": "unknown email"},
+		})
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
+	// a locked account looks just like a wrong password, so locking doesn't reveal that the account exists -
+	// the user is told by email instead
+	if cfg.accountLocked(user) {
+		auth.CheckPasswordHash(params.Password, dummyPasswordHash())
+		cfg.recordIPFailure(r)
+		cfg.recordAudit(r, auditEvent{
+			action:     auditLoginFailed,
+			targetType: auditTargetUser,
+			targetID:   user.ID.String(),
+			diff:       map[string]any{"email": params.Email, "reason": "locked"},
+		})
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", errors.New("account locked"))
+		return
+	}
+
 	// check password matches hash
 	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
 	if err != nil {
+		cfg.recordIPFailure(r)
+		cfg.recordAccountFailure(r, user)
+		cfg.recordAudit(r, auditEvent{
+			action:     auditLoginFailed,
+			targetType: auditTargetUser,
+			targetID:   user.ID.String(),
+			diff:       map[string]any{"email": params.Email, "reason": "incorrect password"},
+		})
 		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
+	// the password is only ever known here, so this is when an outdated hash can be replaced
+	if auth.PasswordNeedsRehash(user.HashedPassword) {
+		cfg.rehashPassword(r, user, params.Password)
+	}
+
+	// suspended users are turned away once they have proven who they are
+	if user.SuspendedAt.Valid {
+		cfg.recordAudit(r, auditEvent{
+			actorID:    user.ID,
+			action:     auditLoginFailed,
+			targetType: auditTargetUser,
+			targetID:   user.ID.String(),
+			diff:       map[string]any{"email": params.Email, "reason": "suspended"},
+		})
+		respondWithError(w, http.StatusForbidden, "Account is suspended", nil)
+		return
+	}
+
+	// with two-factor auth on, the password only earns a challenge, to be completed at /api/login/mfa
+	if user.TotpEnabledAt.Valid {
+		mfaToken, err := auth.MakeMFAToken(user.ID, cfg.jwtKeys, mfaChallengeExpiry)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create MFA challenge", err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, mfaResponse{
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		})
+		return
+	}
+
+	cfg.completeLogin(w, r, user)
+}
+
+// rehashPassword replaces a user's password hash with one made by the current algorithm and parameters.
+// The login doesn't depend on it, so a failure is logged rather than returned.
+func (cfg *apiConfig) rehashPassword(r *http.Request, user database.User, password string) {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		log.Printf("Failed to rehash password for user %s: %s", user.ID, err)
+		return
+	}
+	_, err = cfg.db.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             user.ID,
+		HashedPassword: hash,
+	})
+	if err != nil {
+		log.Printf("Failed to store rehashed password for user %s: %s", user.ID, err)
+	}
+}
+
+// completeLogin issues the tokens for a user who has proven who they are
+func (cfg *apiConfig) completeLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	type response struct {
+		User
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// a successful login starts the count of failures again
+	if user.FailedLoginCount > 0 || user.LockedUntil.Valid {
+		_, err := cfg.db.ClearFailedLogins(r.Context(), user.ID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to clear failed logins", err)
+			return
+		}
+	}
+
 	// auth expires in an hour
 	expirationTime := time.Hour
 
 	// create auth JWT
 	accessToken, err := auth.MakeJWT(
 		user.ID,
-		cfg.jwtSecret,
+		auth.Role(user.Role),
+		cfg.jwtKeys,
 		expirationTime,
 	)
 	if err != nil {
@@ -69,24 +184,30 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 
 	// store refresh token in database
 	storedRefreshToken, err := cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:  refreshTok,
-		UserID: user.ID,
+		Token:     refreshTok,
+		UserID:    user.ID,
+		ExpiresAt: cfg.now().Add(refreshTokenExpiryTime).UTC(),
 		// RevokedAt is null upon creation
+		// each login starts a new family, which the token's replacements will share
+		FamilyID:  uuid.New(),
+		UserAgent: r.UserAgent(),
+		Ip:        clientIP(r),
 	})
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't store refresh token", err)
 		return
 	}
 
+	cfg.recordAudit(r, auditEvent{
+		actorID:    user.ID,
+		action:     auditLoginSucceeded,
+		targetType: auditTargetUser,
+		targetID:   user.ID.String(),
+	})
+
 	// success!
 	respondWithJSON(w, http.StatusOK, response{
-		User: User{
-			ID:        user.ID,
-			CreatedAt: user.CreatedAt,
-			UpdatedAt: user.UpdatedAt,
-			Email:     user.Email,
-			IsPremium: user.IsPremium,
-		},
+		User:         mapUser(user),
 		Token:        accessToken,
 		RefreshToken: storedRefreshToken.Token,
 	})
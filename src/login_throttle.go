@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+	"github.com/wkeebs/chirpy/internal/mail"
+)
+
+// login throttling - failures past a threshold make the next attempt wait, twice as long each time
+const (
+	loginBackoffBase = time.Second
+	loginBackoffMax  = time.Hour
+
+	// accounts start backing off after a few failures, and lock after more
+	accountBackoffAfter = 3
+	accountLockAfter    = 10
+	accountLockDuration = 15 * time.Minute
+	accountLockMax      = 24 * time.Hour
+
+	// addresses get more leeway, as many users can share one, and forget old failures
+	ipBackoffAfter  = 20
+	ipFailureWindow = time.Hour
+)
+
+// backoff is how long to wait after the given number of failures, doubling for each one past the threshold
+func backoff(failures, threshold int32, base, limit time.Duration) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+	d := float64(base) * math.Pow(2, float64(failures-threshold))
+	if d > float64(limit) {
+		return limit
+	}
+	return time.Duration(d)
+}
+
+// dummyPasswordHash is checked against when there is no real hash to check, so a
+// login for an unknown or locked account takes as long as one for a real account
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("chirpy-dummy-password")
+	if err != nil {
+		log.Printf("Failed to create dummy password hash: %s", err)
+	}
+	return hash
+})
+
+// ipRetryAfter returns how long the address must wait before its next login attempt
+func (cfg *apiConfig) ipRetryAfter(r *http.Request) (time.Duration, error) {
+	failures, err := cfg.db.GetLoginIPFailures(r.Context(), clientIP(r))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	now := cfg.now().UTC()
+	if now.Sub(failures.LastFailedAt) > ipFailureWindow {
+		return 0, nil
+	}
+	wait := failures.LastFailedAt.Add(backoff(failures.FailedCount, ipBackoffAfter, loginBackoffBase, loginBackoffMax)).Sub(now)
+	return max(wait, 0), nil
+}
+
+// recordIPFailure counts a failed login against the address it came from
+func (cfg *apiConfig) recordIPFailure(r *http.Request) {
+	ip := clientIP(r)
+	now := cfg.now().UTC()
+
+	// failures outside the window are forgotten, starting the count again
+	failures, err := cfg.db.GetLoginIPFailures(r.Context(), ip)
+	if err == nil && now.Sub(failures.LastFailedAt) > ipFailureWindow {
+		err = cfg.db.ClearLoginIPFailures(r.Context(), ip)
+		if err != nil {
+			log.Printf("Failed to clear login failures for %s: %s", ip, err)
+		}
+	}
+
+	_, err = cfg.db.RecordLoginIPFailure(r.Context(), database.RecordLoginIPFailureParams{
+		Ip:           ip,
+		LastFailedAt: now,
+	})
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %s", ip, err)
+	}
+}
+
+// accountLocked reports whether the user must wait before their next login attempt, either
+// because their account is locked or because they are backing off after recent failures
+func (cfg *apiConfig) accountLocked(user database.User) bool {
+	now := cfg.now().UTC()
+	if user.LockedUntil.Valid && now.Before(user.LockedUntil.Time) {
+		return true
+	}
+	if !user.LastFailedLoginAt.Valid {
+		return false
+	}
+	wait := backoff(user.FailedLoginCount, accountBackoffAfter, loginBackoffBase, loginBackoffMax)
+	return now.Before(user.LastFailedLoginAt.Time.Add(wait))
+}
+
+// recordAccountFailure counts a failed login against the user, locking their account once there
+// have been too many. Each failure after that locks it again, for twice as long.
+func (cfg *apiConfig) recordAccountFailure(r *http.Request, user database.User) {
+	now := cfg.now().UTC()
+	updated, err := cfg.db.RecordFailedLogin(r.Context(), database.RecordFailedLoginParams{
+		ID:                user.ID,
+		LastFailedLoginAt: sql.NullTime{Time: now, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to record login failure for user %s: %s", user.ID, err)
+		return
+	}
+	user = updated
+	if user.FailedLoginCount < accountLockAfter {
+		return
+	}
+
+	lockedUntil := now.Add(backoff(user.FailedLoginCount, accountLockAfter, accountLockDuration, accountLockMax))
+	err = cfg.db.LockUser(r.Context(), database.LockUserParams{
+		ID:          user.ID,
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to lock user %s: %s", user.ID, err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		action:     auditUserLocked,
+		targetType: auditTargetUser,
+		targetID:   user.ID.String(),
+		diff: map[string]any{
+			"failed_logins": user.FailedLoginCount,
+			"locked_until":  lockedUntil,
+		},
+	})
+	cfg.sendLockoutNoticeInBackground(r, user.Email, lockedUntil)
+}
+
+// sendLockoutNoticeInBackground tells the user their account was locked, so they know why they can't
+// log in, and can reset their password if it wasn't them
+func (cfg *apiConfig) sendLockoutNoticeInBackground(r *http.Request, email string, lockedUntil time.Time) {
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		err := cfg.mailer.Send(ctx, mail.Message{
+			To:      email,
+			Subject: "Your Chirpy account has been locked",
+			Body: fmt.Sprintf("There have been too many failed attempts to log in to your Chirpy account, "+
+				"so it has been locked until %s.\n\n"+
+				"If it wasn't you, someone may be trying to guess your password - consider resetting it.\n",
+				lockedUntil.UTC().Format(time.RFC1123)),
+		})
+		if err != nil {
+			log.Printf("Failed to send lockout notice: %s", err)
+		}
+	}()
+}
+
+// respondTooManyLogins turns away an address that is backing off, telling it when to try again
+func respondTooManyLogins(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", ceilSeconds(wait))
+	respondWithError(w, http.StatusTooManyRequests, "Too many login attempts", nil)
+}
+
+// unlockUserHandler - [POST /admin/users/{userID}/unlock] : lifts a login lockout, clearing the user's failed logins
+func (cfg *apiConfig) unlockUserHandler(w http.ResponseWriter, r *http.Request) {
+	// unpack user id
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	user, err := cfg.db.ClearFailedLogins(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlock user", err)
+		return
+	}
+
+	token, _ := cfg.viewer(r)
+	cfg.recordAudit(r, auditEvent{
+		actorID:    token.UserID,
+		action:     auditUserUnlocked,
+		targetType: auditTargetUser,
+		targetID:   user.ID.String(),
+	})
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapUser(user))
+}
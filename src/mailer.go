@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/wkeebs/chirpy/internal/mail"
+)
+
+// newMailer sets up how emails are sent from the MAILER environment variable:
+//   - smtp: through SMTP_HOST and SMTP_PORT, logging in with SMTP_USERNAME and SMTP_PASSWORD if set
+//   - file: saved as .eml files in MAIL_DIR, for development, and the default on the dev platform
+//   - memory: kept in memory, for tests
+//
+// Anywhere else it must be set, so a deploy that forgets it doesn't quietly stop sending emails.
+func newMailer(platform string) (mail.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@chirpy.local>"
+	}
+
+	kind := os.Getenv("MAILER")
+	if kind == "" {
+		if platform != "dev" {
+			return nil, errors.New("MAILER must be set")
+		}
+		kind = "file"
+	}
+
+	switch kind {
+	case "smtp":
+		host := os.Getenv("SMTP_HOST")
+		if host == "" {
+			return nil, errors.New("SMTP_HOST must be set")
+		}
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		return mail.NewSMTPMailer(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from), nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return mail.NewFileMailer(dir, from)
+	case "memory":
+		return mail.NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown MAILER %q: must be smtp, file or memory", kind)
+	}
+}
@@ -1,41 +1,78 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"flag"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	"github.com/wkeebs/chirpy/internal/auth"
 	"github.com/wkeebs/chirpy/internal/database"
+	"github.com/wkeebs/chirpy/internal/mail"
+	"github.com/wkeebs/chirpy/internal/ratelimit"
 )
 
 type apiConfig struct {
 	fileserverHits atomic.Int32 // thread safe
 	db             *database.Queries
 	platform       string
-	jwtSecret      string
+	jwtKeys        *auth.Keyring
 	polkaKey       string
+	trending       *trendingAggregator
+	moderation     *contentModerator
+	mailer         mail.Mailer
+	verifiedOnly   map[string]bool // actions that need a verified email, from REQUIRE_VERIFIED_EMAIL
+	passwords      *auth.PasswordPolicy
+	now            func() time.Time // the clock TOTP codes are checked against
 }
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
-	IsPremium bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	PendingEmail  string    `json:"pending_email,omitempty"` // waiting to be confirmed from the new address
+	IsPremium     bool      `json:"is_chirpy_red"`
+	Role          string    `json:"role"`
+	Handle        string    `json:"handle,omitempty"`
+	DisplayName   string    `json:"display_name"`
+	Bio           string    `json:"bio"`
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	UserID    uuid.UUID  `json:"user_id"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	RootID    *uuid.UUID `json:"root_id,omitempty"`
+	Deleted   bool       `json:"deleted,omitempty"` // tombstone left behind when a chirp with replies is deleted
+	Held      bool       `json:"held,omitempty"`    // held for review, so only its author can see it
+	Hidden    bool       `json:"hidden,omitempty"`  // taken down by a moderator, so only its author can see it
+	LikeCount int32      `json:"like_count"`
+	LikedByMe bool       `json:"liked_by_me"`
+	Kind      string     `json:"kind"`
+	RechirpOf *ChirpRef  `json:"rechirp_of,omitempty"`
+	QuoteOf   *ChirpRef  `json:"quote_of,omitempty"`
+	Mentions  []Mention  `json:"mentions"`
+}
+
+// ChirpRef is a chirp embedded in a rechirp or quote. When the original
+// has been deleted only the placeholder fields are set.
+type ChirpRef struct {
+	*Chirp
+	Unavailable bool   `json:"unavailable,omitempty"`
+	Message     string `json:"message,omitempty"`
 }
 
 func readinessHandler(w http.ResponseWriter, _ *http.Request) {
@@ -45,17 +82,46 @@ func readinessHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 func main() {
+	// -promote makes an existing user an admin (or -role) and exits, to bootstrap the first admin
+	promoteEmail := flag.String("promote", "", "email of a user to promote, instead of serving")
+	promoteRole := flag.String("role", string(auth.RoleAdmin), "role to give the user named by -promote")
+	// -generate-key writes a new signing key to JWT_KEYS_DIR and exits, as the first step of a key rotation
+	generateKID := flag.String("generate-key", "", "id of a new signing key to generate, instead of serving")
+	keyAlg := flag.String("key-alg", auth.AlgEdDSA, "algorithm of the key made by -generate-key (RS256 or EdDSA)")
+	flag.Parse()
+
 	godotenv.Load() // get env
 
+	// JWT signing keys, named by their kid
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if *generateKID != "" {
+		if keysDir == "" {
+			log.Fatal("JWT_KEYS_DIR environment variable is not set")
+		}
+		path, err := auth.GenerateKey(keysDir, *generateKID, *keyAlg)
+		if err != nil {
+			log.Fatalf("Error generating key: %s", err)
+		}
+		log.Printf("Wrote %s key %s to %s\n", *keyAlg, *generateKID, path)
+		return
+	}
+
 	// get platform
 	platform := os.Getenv("PLATFORM")
 	if platform == "" {
 		log.Fatal("PLATFORM must be set")
 	}
 
-	// get JWT secret
+	// get JWT keys - with a keys dir, the secret is only kept to accept tokens signed before the switch
 	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
+	jwtKeys := auth.NewHMACKeyring(jwtSecret)
+	if keysDir != "" {
+		var err error
+		jwtKeys, err = auth.LoadKeyring(keysDir, os.Getenv("JWT_ACTIVE_KEY"), jwtSecret)
+		if err != nil {
+			log.Fatalf("Error loading JWT keys: %s", err)
+		}
+	} else if jwtSecret == "" {
 		log.Fatal("JWT_SECRET environment variable is not set")
 	}
 
@@ -77,6 +143,44 @@ func main() {
 	}
 	dbQueries := database.New(dbConn)
 
+	if *promoteEmail != "" {
+		err = promoteUser(context.Background(), dbQueries, *promoteEmail, *promoteRole)
+		if err != nil {
+			log.Fatalf("Error promoting user: %s", err)
+		}
+		return
+	}
+
+	// extra moderation words can be loaded from a file, alongside those kept in the db
+	moderator, err := newContentModerator(dbQueries, os.Getenv("MODERATION_WORDS_FILE"))
+	if err != nil {
+		log.Fatalf("Error loading moderation words: %s", err)
+	}
+
+	mailer, err := newMailer(platform)
+	if err != nil {
+		log.Fatalf("Error setting up mailer: %s", err)
+	}
+
+	// some actions can be kept from users who haven't verified their email
+	verifiedOnly, err := parseVerifiedEmailActions(os.Getenv("REQUIRE_VERIFIED_EMAIL"))
+	if err != nil {
+		log.Fatalf("Error reading REQUIRE_VERIFIED_EMAIL: %s", err)
+	}
+
+	// new passwords have a minimum length, and can be checked against a list of common ones
+	minPasswordLength := defaultMinPasswordLength
+	if s := os.Getenv("PASSWORD_MIN_LENGTH"); s != "" {
+		minPasswordLength, err = strconv.Atoi(s)
+		if err != nil {
+			log.Fatalf("Error reading PASSWORD_MIN_LENGTH: %s", err)
+		}
+	}
+	passwordPolicy, err := auth.NewPasswordPolicy(minPasswordLength, os.Getenv("COMMON_PASSWORDS_FILE"))
+	if err != nil {
+		log.Fatalf("Error loading common passwords: %s", err)
+	}
+
 	// setup serving
 	const filepathRoot = "."
 	const port = "8080"
@@ -84,10 +188,22 @@ func main() {
 		fileserverHits: atomic.Int32{},
 		db:             dbQueries,
 		platform:       platform,
-		jwtSecret:      jwtSecret,
+		jwtKeys:        jwtKeys,
 		polkaKey:       polkaKey,
+		trending:       newTrendingAggregator(dbQueries),
+		moderation:     moderator,
+		mailer:         mailer,
+		verifiedOnly:   verifiedOnly,
+		passwords:      passwordPolicy,
+		now:            time.Now,
 	}
 
+	// trending hashtags are aggregated in the background rather than per request
+	go apiCfg.trending.run(context.Background())
+
+	// moderation words are reloaded in the background to pick up changes from other instances
+	go apiCfg.moderation.run(context.Background())
+
 	mux := http.NewServeMux()
 	fsHandler := apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir(filepathRoot))))
 	mux.Handle("/app/", fsHandler) // file server handler
@@ -96,19 +212,56 @@ func main() {
 	// -- healthz
 	mux.HandleFunc("GET /api/healthz", readinessHandler)
 
+	// -- public keys for verifying access tokens
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.jwksHandler)
+
 	// -- chirps
 	mux.HandleFunc("GET /api/chirps", apiCfg.getAllChirpsHandler)
+	mux.HandleFunc("GET /api/chirps/search", apiCfg.searchChirpsHandler)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirpHandler)
-	mux.HandleFunc("POST /api/chirps", apiCfg.createChirpHandler)
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.getThreadHandler)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/likers", apiCfg.getLikersHandler)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/like", apiCfg.authenticate(auth.ScopeChirpsWrite, apiCfg.likeChirpHandler))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/rechirp", apiCfg.authenticate(auth.ScopeChirpsWrite, apiCfg.rechirpHandler))
+	mux.HandleFunc("POST /api/chirps/{chirpID}/report", apiCfg.authenticate(auth.ScopeChirpsWrite, apiCfg.reportChirpHandler))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/like", apiCfg.authenticate(auth.ScopeChirpsWrite, apiCfg.unlikeChirpHandler))
+	mux.HandleFunc("POST /api/chirps", apiCfg.authenticate(auth.ScopeChirpsWrite, apiCfg.createChirpHandler))
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.authenticate(auth.ScopeChirpsWrite, apiCfg.deleteChirpHandler))
+
+	// -- hashtags
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.getHashtagChirpsHandler)
+	mux.HandleFunc("GET /api/trending", apiCfg.trendingHandler)
 
 	// -- users
 	mux.HandleFunc("GET /api/users", apiCfg.getAllUsersHandler)
 	mux.HandleFunc("POST /api/users", apiCfg.createUserHandler)
-	mux.HandleFunc("PUT /api/users", apiCfg.updateUserHandler)
+	mux.HandleFunc("PUT /api/users", apiCfg.authenticate(auth.ScopeProfileWrite, apiCfg.updateUserHandler))
+	mux.HandleFunc("GET /api/users/me/mentions", apiCfg.authenticate(auth.ScopeChirpsRead, apiCfg.getMyMentionsHandler))
+	mux.HandleFunc("POST /api/users/me/totp", apiCfg.startTOTPHandler)
+	mux.HandleFunc("POST /api/users/me/totp/confirm", apiCfg.confirmTOTPHandler)
+	mux.HandleFunc("POST /api/users/me/totp/disable", apiCfg.disableTOTPHandler)
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.getProfileHandler)
+
+	// -- follows
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.authenticate(auth.ScopeProfileWrite, apiCfg.followUserHandler))
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.authenticate(auth.ScopeProfileWrite, apiCfg.unfollowUserHandler))
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.getFollowersHandler)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.getFollowingHandler)
+
+	// -- timeline
+	mux.HandleFunc("GET /api/timeline", apiCfg.authenticate(auth.ScopeChirpsRead, apiCfg.timelineHandler))
 
 	// -- login
 	mux.HandleFunc("POST /api/login", apiCfg.loginHandler)
+	mux.HandleFunc("POST /api/login/mfa", apiCfg.loginMFAHandler)
+
+	// -- password reset
+	mux.HandleFunc("POST /api/password-reset/request", apiCfg.requestPasswordResetHandler)
+	mux.HandleFunc("POST /api/password-reset/confirm", apiCfg.confirmPasswordResetHandler)
+
+	// -- email verification
+	mux.HandleFunc("POST /api/email-verification/request", apiCfg.requestEmailVerificationHandler)
+	mux.HandleFunc("POST /api/email-verification/confirm", apiCfg.confirmEmailVerificationHandler)
 
 	// -- refresh token
 	mux.HandleFunc("POST /api/refresh", apiCfg.refreshHandler)
@@ -116,16 +269,59 @@ func main() {
 	// -- revoke token
 	mux.HandleFunc("POST /api/revoke", apiCfg.revokeHandler)
 
+	// -- sessions
+	mux.HandleFunc("GET /api/sessions", apiCfg.getSessionsHandler)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.revokeSessionHandler)
+	mux.HandleFunc("POST /api/sessions/revoke-all", apiCfg.revokeAllSessionsHandler)
+
+	// -- personal access tokens
+	mux.HandleFunc("GET /api/tokens", apiCfg.getPersonalAccessTokensHandler)
+	mux.HandleFunc("POST /api/tokens", apiCfg.createPersonalAccessTokenHandler)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.revokePersonalAccessTokenHandler)
+
+	// -- oauth
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.getOAuthClientsHandler)
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.createOAuthClientHandler)
+	mux.HandleFunc("GET /api/oauth/clients/{clientID}", apiCfg.getOAuthClientHandler)
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.deleteOAuthClientHandler)
+	mux.HandleFunc("GET /api/oauth/authorize", apiCfg.authorizeHandler)
+	mux.HandleFunc("POST /api/oauth/authorize", apiCfg.consentHandler)
+	mux.HandleFunc("POST /api/oauth/token", apiCfg.tokenHandler)
+	mux.HandleFunc("POST /api/oauth/introspect", apiCfg.introspectHandler)
+	mux.HandleFunc("POST /api/oauth/revoke", apiCfg.revokeOAuthTokenHandler)
+
 	// -- polka (premium webhook simulator)
 	mux.HandleFunc("POST /api/polka/webhooks", apiCfg.upgradeUserHandler)
 
 	// other handlers
-	mux.HandleFunc("GET /admin/metrics", apiCfg.metricsHandler)
-	mux.HandleFunc("POST /admin/reset", apiCfg.resetHandler)
+	mux.HandleFunc("GET /admin/metrics", apiCfg.requireRole(auth.RoleAdmin, apiCfg.metricsHandler))
+	mux.HandleFunc("POST /admin/reset", apiCfg.requireRole(auth.RoleAdmin, apiCfg.resetHandler))
+	mux.HandleFunc("PUT /admin/users/{userID}/role", apiCfg.requireRole(auth.RoleAdmin, apiCfg.setUserRoleHandler))
+	mux.HandleFunc("POST /admin/users/{userID}/unlock", apiCfg.requireRole(auth.RoleAdmin, apiCfg.unlockUserHandler))
+	mux.HandleFunc("GET /admin/audit", apiCfg.requireRole(auth.RoleAdmin, apiCfg.getAuditEventsHandler))
+
+	// -- moderation
+	mux.HandleFunc("GET /admin/moderation/words", apiCfg.requireRole(auth.RoleModerator, apiCfg.getModerationWordsHandler))
+	mux.HandleFunc("POST /admin/moderation/words", apiCfg.requireRole(auth.RoleModerator, apiCfg.addModerationWordHandler))
+	mux.HandleFunc("DELETE /admin/moderation/words/{word}", apiCfg.requireRole(auth.RoleModerator, apiCfg.deleteModerationWordHandler))
+	mux.HandleFunc("GET /admin/reports", apiCfg.requireRole(auth.RoleModerator, apiCfg.getReportsHandler))
+	mux.HandleFunc("POST /admin/reports/{reportID}/resolve", apiCfg.requireRole(auth.RoleModerator, apiCfg.resolveReportHandler))
+
+	// rate limits are checked before requests reach the mux
+	rateLimits, err := parseRateLimits(os.Getenv("RATE_LIMITS"), defaultRateLimits)
+	if err != nil {
+		log.Fatalf("Error reading RATE_LIMITS: %s", err)
+	}
+	rateLimitStore := ratelimit.NewMemoryStore()
+	go rateLimitStore.Run(context.Background(), rateLimitSweepInterval)
+	limiter, err := newRateLimiter(rateLimitStore, rateLimits, apiCfg.viewer, apiCfg.now)
+	if err != nil {
+		log.Fatalf("Error setting up rate limits: %s", err)
+	}
 
 	srv := &http.Server{
 		Addr:    ":" + port,
-		Handler: mux,
+		Handler: limiter.middleware(mux),
 	}
 
 	log.Printf("Serving files from %s on port: %s\n", filepathRoot, port)
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+type Mention struct {
+	UserID uuid.UUID `json:"user_id"`
+	Handle string    `json:"handle"`
+}
+
+// a mention is an @ followed by a handle, and can't be glued onto a preceding word (so emails don't count)
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([A-Za-z0-9_]+)`)
+
+// extractMentions returns the distinct, lowercased handles mentioned in a chirp body
+func extractMentions(body string) []string {
+	var handles []string
+	seen := map[string]bool{}
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		handle := strings.ToLower(match[1])
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+	return handles
+}
+
+// recordMentions resolves the handles mentioned in a chirp and stores a mention for each
+// known user. Unknown handles are left as plain text.
+func (cfg *apiConfig) recordMentions(r *http.Request, chirp database.Chirp) error {
+	handles := extractMentions(chirp.Body)
+	if len(handles) == 0 {
+		return nil
+	}
+
+	users, err := cfg.db.GetUsersByHandles(r.Context(), handles)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(users))
+	for _, u := range users {
+		userIDs = append(userIDs, u.ID)
+	}
+	return cfg.db.AddChirpMentions(r.Context(), database.AddChirpMentionsParams{
+		ChirpID: chirp.ID,
+		UserIds: userIDs,
+	})
+}
+
+// getMyMentionsHandler - [GET /api/users/me/mentions] : serves chirps mentioning the caller, newest first
+func (cfg *apiConfig) getMyMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// reading forward means descending order
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(cursor)
+		if forward {
+			return cfg.db.ListMentionsDesc(r.Context(), database.ListMentionsDescParams{
+				UserID:          userID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		}
+		return cfg.db.ListMentionsAsc(r.Context(), database.ListMentionsAscParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+	}
+
+	chirps, err := paginate(r, pageParams, fetch, chirpCursor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get mentions", err)
+		return
+	}
+
+	// map for correct json representation
+	items, err := cfg.mapChirps(r, chirps.Items)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to map Chirps", err)
+		return
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, page[Chirp]{
+		Items: items,
+		Next:  chirps.Next,
+		Prev:  chirps.Prev,
+	})
+}
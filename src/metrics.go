@@ -21,6 +21,14 @@ func (cfg *apiConfig) resetHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the audit log is kept through resets
+	cfg.recordAudit(r, auditEvent{
+		actorID:    cfg.viewerID(r),
+		action:     auditAdminReset,
+		targetType: auditTargetSystem,
+		diff:       map[string]any{"users": "deleted all", "fileserver_hits": auditChange{From: cfg.fileserverHits.Load(), To: 0}},
+	})
+
 	// write response
 	w.WriteHeader(http.StatusOK)
 	cfg.fileserverHits.Store(0)
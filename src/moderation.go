@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+	"github.com/wkeebs/chirpy/internal/moderation"
+)
+
+// chirp moderation statuses - held chirps are waiting for review and hidden chirps have been
+// taken down by a moderator. Either way they are only shown to their author and moderators.
+const (
+	moderationStatusVisible = "visible"
+	moderationStatusHeld    = "held"
+	moderationStatusHidden  = "hidden"
+)
+
+const moderationRefreshInterval = time.Minute
+
+type ModerationWord struct {
+	Word      string    `json:"word"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// contentModerator runs chirp bodies through a chain of word list filters - one loaded
+// from a file at startup, and one kept in the database so it can be managed at runtime.
+// The database list is reloaded whenever it changes and periodically, to pick up edits
+// made through other instances.
+type contentModerator struct {
+	db       *database.Queries
+	fileList *moderation.WordListFilter
+	dbList   *moderation.WordListFilter
+	chain    moderation.Chain
+}
+
+func newContentModerator(db *database.Queries, wordsFile string) (*contentModerator, error) {
+	m := &contentModerator{
+		db:       db,
+		fileList: moderation.NewWordListFilter(moderation.FoldUnicode, moderation.FoldLeetspeak),
+		dbList:   moderation.NewWordListFilter(moderation.FoldUnicode, moderation.FoldLeetspeak),
+	}
+	m.chain = moderation.Chain{m.fileList, m.dbList}
+
+	if wordsFile != "" {
+		words, err := moderation.LoadWordsFile(wordsFile)
+		if err != nil {
+			return nil, err
+		}
+		m.fileList.SetWords(words)
+	}
+	return m, nil
+}
+
+// run reloads the database word list straight away, then on every tick until the context is done
+func (m *contentModerator) run(ctx context.Context) {
+	ticker := time.NewTicker(moderationRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		err := m.refresh(ctx)
+		if err != nil {
+			// keep filtering with the last good list
+			log.Printf("Failed to refresh moderation words: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *contentModerator) refresh(ctx context.Context) error {
+	rows, err := m.db.ListModerationWords(ctx)
+	if err != nil {
+		return err
+	}
+
+	words := make([]moderation.Word, 0, len(rows))
+	for _, row := range rows {
+		words = append(words, moderation.Word{Word: row.Word, Action: moderation.Action(row.Action)})
+	}
+	m.dbList.SetWords(words)
+	return nil
+}
+
+// check runs text through every filter
+func (m *contentModerator) check(text string) moderation.Result {
+	return m.chain.Filter(text)
+}
+
+// canSeeChirp reports whether the request may see a chirp - chirps that are held or hidden
+// are only shown to their author and moderators
+func (cfg *apiConfig) canSeeChirp(r *http.Request, chirp database.Chirp) bool {
+	if chirp.ModerationStatus == moderationStatusVisible {
+		return true
+	}
+	token, ok := cfg.viewer(r)
+	return ok && (chirp.UserID == token.UserID || token.Role.Has(auth.RoleModerator))
+}
+
+// getModerationWordsHandler - [GET /admin/moderation/words] : lists the moderation word list
+func (cfg *apiConfig) getModerationWordsHandler(w http.ResponseWriter, r *http.Request) {
+	words, err := cfg.db.ListModerationWords(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get moderation words", err)
+		return
+	}
+
+	// map for correct json representation
+	respWords := make([]ModerationWord, 0, len(words))
+	for _, word := range words {
+		respWords = append(respWords, mapModerationWord(word))
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respWords)
+}
+
+// addModerationWordHandler - [POST /admin/moderation/words] : adds a word to the list, or changes its action
+func (cfg *apiConfig) addModerationWordHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Word   string `json:"word"`
+		Action string `json:"action"`
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// words are stored as typed, and folded when the list is loaded
+	word := strings.TrimSpace(params.Word)
+	if word == "" || strings.ContainsFunc(word, unicode.IsSpace) {
+		err = errors.New("word must be a single word")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	if params.Action == "" {
+		params.Action = string(moderation.ActionMask)
+	}
+	action, err := moderation.ParseAction(params.Action)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	stored, err := cfg.db.UpsertModerationWord(r.Context(), database.UpsertModerationWordParams{
+		Word:   strings.ToLower(word),
+		Action: string(action),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to save moderation word", err)
+		return
+	}
+
+	// apply the change straight away on this instance
+	err = cfg.moderation.refresh(r.Context())
+	if err != nil {
+		log.Printf("Failed to refresh moderation words: %s", err)
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapModerationWord(stored))
+}
+
+// deleteModerationWordHandler - [DELETE /admin/moderation/words/{word}] : removes a word from the list
+func (cfg *apiConfig) deleteModerationWordHandler(w http.ResponseWriter, r *http.Request) {
+	deleted, err := cfg.db.DeleteModerationWord(r.Context(), strings.ToLower(r.PathValue("word")))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete moderation word", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Word is not on the list", nil)
+		return
+	}
+
+	// apply the change straight away on this instance
+	err = cfg.moderation.refresh(r.Context())
+	if err != nil {
+		log.Printf("Failed to refresh moderation words: %s", err)
+	}
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mapModerationWord converts a database word list entry into its json representation
+func mapModerationWord(w database.ModerationWord) ModerationWord {
+	return ModerationWord{
+		Word:      w.Word,
+		Action:    w.Action,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+const (
+	// consentPagePath is where users are sent to approve an authorization request, served by the file server
+	consentPagePath = "/app/oauth/authorize.html"
+
+	oauthAccessTokenExpiry = time.Hour
+)
+
+// error codes from RFC 6749, 7009 and 7662
+const (
+	oauthErrInvalidRequest          = "invalid_request"
+	oauthErrInvalidClient           = "invalid_client"
+	oauthErrInvalidGrant            = "invalid_grant"
+	oauthErrInvalidScope            = "invalid_scope"
+	oauthErrAccessDenied            = "access_denied"
+	oauthErrUnsupportedGrantType    = "unsupported_grant_type"
+	oauthErrUnsupportedResponseType = "unsupported_response_type"
+	oauthErrServerError             = "server_error"
+)
+
+// oauthError is an error in the shape OAuth clients expect, either as json or in a redirect
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// respondWithOAuthError writes an error from the token, introspection or revocation endpoints
+func respondWithOAuthError(w http.ResponseWriter, code int, oauthErr *oauthError, err error) {
+	if err != nil {
+		log.Println(err)
+	}
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, oauthErr)
+}
+
+// authorizationParams are the parameters of an authorization request, as the client sent them
+type authorizationParams struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+// authorizationRequest is a checked authorization request
+type authorizationRequest struct {
+	client        database.OauthClient
+	redirectURI   string
+	state         string
+	scopes        []auth.Scope
+	codeChallenge string
+}
+
+// redirect builds the URL that sends the user back to the client with the given parameters
+func (req authorizationRequest) redirect(params url.Values) string {
+	if req.state != "" {
+		params.Set("state", req.state)
+	}
+	u, _ := url.Parse(req.redirectURI)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// parseAuthorizationRequest checks an authorization request. Until the client and redirect URI are known to be
+// good there is nowhere safe to send the user back to, so those problems are returned as plain errors. Problems
+// after that are returned as an *oauthError, along with the request they can be redirected back to the client in.
+func (cfg *apiConfig) parseAuthorizationRequest(ctx context.Context, params authorizationParams) (authorizationRequest, error) {
+	req := authorizationRequest{state: params.State}
+
+	clientID, err := uuid.Parse(params.ClientID)
+	if err != nil {
+		return req, errors.New("invalid client_id")
+	}
+	req.client, err = cfg.db.GetOAuthClient(ctx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return req, errors.New("unknown client_id")
+	}
+	if err != nil {
+		return req, err
+	}
+
+	// the redirect URI must be one the client registered, and can be left out if it only has one
+	switch {
+	case slices.Contains(req.client.RedirectUris, params.RedirectURI):
+		req.redirectURI = params.RedirectURI
+	case params.RedirectURI == "" && len(req.client.RedirectUris) == 1:
+		req.redirectURI = req.client.RedirectUris[0]
+	default:
+		return req, errors.New("redirect_uri is not registered for this client")
+	}
+
+	if params.ResponseType != "code" {
+		return req, &oauthError{Code: oauthErrUnsupportedResponseType, Description: "response_type must be code"}
+	}
+	req.scopes, err = auth.ParseScopeString(params.Scope)
+	if err != nil {
+		return req, &oauthError{Code: oauthErrInvalidScope, Description: err.Error()}
+	}
+	if len(req.scopes) == 0 {
+		return req, &oauthError{Code: oauthErrInvalidScope, Description: "scope is required"}
+	}
+
+	// every client must use PKCE, confidential or not
+	if !auth.ValidPKCEChallenge(params.CodeChallenge) {
+		return req, &oauthError{Code: oauthErrInvalidRequest, Description: "a valid code_challenge is required"}
+	}
+	if params.CodeChallengeMethod != auth.PKCEMethodS256 {
+		return req, &oauthError{Code: oauthErrInvalidRequest, Description: "code_challenge_method must be S256"}
+	}
+	req.codeChallenge = params.CodeChallenge
+	return req, nil
+}
+
+// authorizeHandler - [GET /api/oauth/authorize] : starts the authorization code flow, sending the user on to the consent page
+func (cfg *apiConfig) authorizeHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req, err := cfg.parseAuthorizationRequest(r.Context(), authorizationParams{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	})
+
+	// errors the client can be told about go back to it, anything else is shown to the user
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		http.Redirect(w, r, req.redirect(url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+		}), http.StatusFound)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// the consent page reads the request back out of its query string
+	http.Redirect(w, r, consentPagePath+"?"+r.URL.RawQuery, http.StatusFound)
+}
+
+// consentHandler - [POST /api/oauth/authorize] : records the user's answer on the consent page, returning where to send them next
+func (cfg *apiConfig) consentHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		authorizationParams
+		Approved bool `json:"approved"`
+	}
+	type response struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+
+	// check access token - only a real login can approve a client
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	req, err := cfg.parseAuthorizationRequest(r.Context(), params.authorizationParams)
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		respondWithJSON(w, http.StatusOK, response{RedirectTo: req.redirect(url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+		})})
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if !params.Approved {
+		respondWithJSON(w, http.StatusOK, response{RedirectTo: req.redirect(url.Values{
+			"error":             {oauthErrAccessDenied},
+			"error_description": {"the user denied the request"},
+		})})
+		return
+	}
+
+	// only the code's hash is stored - the client proves it started the flow with the PKCE verifier
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create authorization code", err)
+		return
+	}
+	err = cfg.db.CreateAuthorizationCode(r.Context(), database.CreateAuthorizationCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.client.ID,
+		UserID:        userID,
+		RedirectUri:   req.redirectURI,
+		Scopes:        auth.ScopeStrings(req.scopes),
+		CodeChallenge: req.codeChallenge,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to store authorization code", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditOAuthAuthorized,
+		targetType: auditTargetOAuthClient,
+		targetID:   req.client.ID.String(),
+		diff:       map[string]any{"scopes": auth.ScopeStrings(req.scopes)},
+	})
+
+	// write response
+	respondWithJSON(w, http.StatusOK, response{RedirectTo: req.redirect(url.Values{"code": {code}})})
+}
+
+// authenticateClient identifies the client calling the token, introspection or revocation endpoints, with
+// HTTP Basic auth or client_id and client_secret in the form. Public clients only need their client_id.
+func (cfg *apiConfig) authenticateClient(r *http.Request) (database.OauthClient, error) {
+	clientIDString, secret, basic := r.BasicAuth()
+	if basic {
+		// both halves are form encoded before they go in the header
+		var err error
+		clientIDString, err = url.QueryUnescape(clientIDString)
+		if err != nil {
+			return database.OauthClient{}, err
+		}
+		secret, err = url.QueryUnescape(secret)
+		if err != nil {
+			return database.OauthClient{}, err
+		}
+	} else {
+		clientIDString = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	clientID, err := uuid.Parse(clientIDString)
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+
+	if !client.SecretHash.Valid {
+		if secret != "" {
+			return database.OauthClient{}, errors.New("public clients have no secret")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, errors.New("incorrect client secret")
+	}
+	return client, nil
+}
+
+// parseClientForm reads the form of a request to the token, introspection or revocation endpoints, and identifies
+// the client making it. It responds with an error itself if either fails.
+func (cfg *apiConfig) parseClientForm(w http.ResponseWriter, r *http.Request) (database.OauthClient, bool) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: oauthErrInvalidRequest, Description: "invalid form"}, err)
+		return database.OauthClient{}, false
+	}
+
+	client, err := cfg.authenticateClient(r)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusUnauthorized, &oauthError{Code: oauthErrInvalidClient, Description: "client authentication failed"}, err)
+		return database.OauthClient{}, false
+	}
+	return client, true
+}
+
+// tokenHandler - [POST /api/oauth/token] : exchanges an authorization code or refresh token for new tokens
+func (cfg *apiConfig) tokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := cfg.parseClientForm(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.exchangeAuthorizationCode(w, r, client)
+	case "refresh_token":
+		cfg.exchangeOAuthRefreshToken(w, r, client)
+	default:
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{
+			Code:        oauthErrUnsupportedGrantType,
+			Description: "grant_type must be authorization_code or refresh_token",
+		}, nil)
+	}
+}
+
+// exchangeAuthorizationCode swaps a code for the client's first tokens, starting a session in the code's family
+func (cfg *apiConfig) exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	invalidGrant := &oauthError{Code: oauthErrInvalidGrant, Description: "invalid authorization code"}
+	codeHash := auth.HashToken(r.PostForm.Get("code"))
+
+	// using the code marks it used, so it can only ever work once
+	code, err := cfg.db.UseAuthorizationCode(r.Context(), codeHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		// a code presented twice has been intercepted, so whatever was issued for it is revoked
+		used, err := cfg.db.GetAuthorizationCode(r.Context(), codeHash)
+		if err == nil && used.UsedAt.Valid {
+			cfg.revokeReusedCode(r, used)
+		}
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, errors.New("authorization code used or expired"))
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+		return
+	}
+
+	// the code must come back from the client it was issued to, with the same redirect URI and the PKCE verifier
+	if code.ClientID != client.ID || code.RedirectUri != r.PostForm.Get("redirect_uri") {
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, errors.New("authorization code used by another client or redirect_uri"))
+		return
+	}
+	if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, errors.New("code_verifier does not match"))
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), code.UserID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, err)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: oauthErrInvalidGrant, Description: "account is suspended"}, nil)
+		return
+	}
+
+	scopes, err := auth.ParseScopes(code.Scopes)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+		return
+	}
+	cfg.issueOAuthTokens(w, r, client, user.ID, scopes, code.FamilyID, sql.NullString{}, cfg.now().Add(refreshTokenExpiryTime).UTC())
+}
+
+// revokeReusedCode revokes the session started with an authorization code that was presented again, and records the reuse
+func (cfg *apiConfig) revokeReusedCode(r *http.Request, code database.OauthAuthorizationCode) {
+	log.Printf("Authorization code reuse detected for user %s, revoking token family %s", code.UserID, code.FamilyID)
+
+	err := cfg.db.RevokeTokenFamily(r.Context(), code.FamilyID)
+	if err != nil {
+		log.Printf("Failed to revoke token family %s: %s", code.FamilyID, err)
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		action:     auditTokenReused,
+		targetType: auditTargetTokenFamily,
+		targetID:   code.FamilyID.String(),
+		diff: map[string]any{
+			"user_id":   code.UserID,
+			"client_id": code.ClientID,
+		},
+	})
+}
+
+// exchangeOAuthRefreshToken rotates a client's refresh token the same way /api/refresh does, optionally narrowing its scopes
+func (cfg *apiConfig) exchangeOAuthRefreshToken(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	invalidGrant := &oauthError{Code: oauthErrInvalidGrant, Description: "invalid refresh token"}
+	refreshTok := r.PostForm.Get("refresh_token")
+
+	// look up token - it must have been issued to this client
+	storedToken, err := cfg.db.GetRefreshToken(r.Context(), refreshTok)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, err)
+		return
+	}
+	if !storedToken.ClientID.Valid || storedToken.ClientID.UUID != client.ID {
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, errors.New("refresh token issued to another client"))
+		return
+	}
+
+	// a token that has already been rotated should never be seen again
+	if storedToken.RotatedAt.Valid {
+		cfg.revokeReusedFamily(r, storedToken)
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, errors.New("refresh token reused"))
+		return
+	}
+	if storedToken.RevokedAt.Valid || storedToken.ExpiresAt.Before(time.Now()) {
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, errors.New("refresh token revoked or expired"))
+		return
+	}
+
+	// the client can ask for fewer scopes than it was granted, but never more
+	scopes, err := auth.ParseScopes(storedToken.Scopes)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+		return
+	}
+	if requested := r.PostForm.Get("scope"); requested != "" {
+		narrowed, err := auth.ParseScopeString(requested)
+		if err != nil {
+			respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: oauthErrInvalidScope, Description: err.Error()}, err)
+			return
+		}
+		for _, scope := range narrowed {
+			if !slices.Contains(scopes, scope) {
+				respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: oauthErrInvalidScope, Description: "scope exceeds what was granted"}, nil)
+				return
+			}
+		}
+		scopes = narrowed
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), storedToken.UserID)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, err)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithOAuthError(w, http.StatusBadRequest, &oauthError{Code: oauthErrInvalidGrant, Description: "account is suspended"}, nil)
+		return
+	}
+
+	// retire the old token - if it was rotated since we looked it up, it was used twice at once
+	_, err = cfg.db.RotateRefreshToken(r.Context(), refreshTok)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.revokeReusedFamily(r, storedToken)
+		respondWithOAuthError(w, http.StatusBadRequest, invalidGrant, errors.New("refresh token reused"))
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+		return
+	}
+
+	cfg.issueOAuthTokens(w, r, client, user.ID, scopes, storedToken.FamilyID, sql.NullString{String: refreshTok, Valid: true}, storedToken.ExpiresAt)
+}
+
+// issueOAuthTokens responds with a scoped access token and a refresh token in the given family, expiring when
+// the family does
+func (cfg *apiConfig) issueOAuthTokens(
+	w http.ResponseWriter,
+	r *http.Request,
+	client database.OauthClient,
+	userID uuid.UUID,
+	scopes []auth.Scope,
+	familyID uuid.UUID,
+	parentToken sql.NullString,
+	expiresAt time.Time,
+) {
+	type response struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}
+
+	refreshTok, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+		return
+	}
+	_, err = cfg.db.CreateOAuthRefreshToken(r.Context(), database.CreateOAuthRefreshTokenParams{
+		Token:       refreshTok,
+		UserID:      userID,
+		FamilyID:    familyID,
+		ExpiresAt:   expiresAt,
+		ParentToken: parentToken,
+		UserAgent:   r.UserAgent(),
+		Ip:          clientIP(r),
+		ClientID:    uuid.NullUUID{UUID: client.ID, Valid: true},
+		Scopes:      auth.ScopeStrings(scopes),
+	})
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+		return
+	}
+
+	accessToken, err := auth.MakeScopedJWT(userID, client.ID, familyID, scopes, cfg.jwtKeys, oauthAccessTokenExpiry)
+	if err != nil {
+		respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+		return
+	}
+
+	// success - tokens must never be cached
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, response{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenExpiry.Seconds()),
+		RefreshToken: refreshTok,
+		Scope:        auth.FormatScopes(scopes),
+	})
+}
+
+// introspectHandler - [POST /api/oauth/introspect] : describes one of the client's tokens, per RFC 7662
+func (cfg *apiConfig) introspectHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Subject   string `json:"sub,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		ExpiresAt int64  `json:"exp,omitempty"`
+		IssuedAt  int64  `json:"iat,omitempty"`
+	}
+
+	client, ok := cfg.parseClientForm(w, r)
+	if !ok {
+		return
+	}
+	tokenString := r.PostForm.Get("token")
+	w.Header().Set("Cache-Control", "no-store")
+
+	// an access token is active while it is valid and its session hasn't been revoked
+	if token, err := auth.ParseJWT(tokenString, cfg.jwtKeys); err == nil && token.ClientID == client.ID {
+		active, err := cfg.db.TokenFamilyActive(r.Context(), token.SessionID)
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+			return
+		}
+		if !active {
+			respondWithJSON(w, http.StatusOK, response{})
+			return
+		}
+		respondWithJSON(w, http.StatusOK, response{
+			Active:    true,
+			Scope:     auth.FormatScopes(token.Scopes),
+			ClientID:  client.ID.String(),
+			Subject:   token.UserID.String(),
+			TokenType: "Bearer",
+			ExpiresAt: token.ExpiresAt.Unix(),
+			IssuedAt:  token.IssuedAt.Unix(),
+		})
+		return
+	}
+
+	// a refresh token is active until it is rotated, revoked or expires
+	storedToken, err := cfg.db.GetRefreshToken(r.Context(), tokenString)
+	if err == nil && storedToken.ClientID.Valid && storedToken.ClientID.UUID == client.ID &&
+		!storedToken.RevokedAt.Valid && storedToken.ExpiresAt.After(time.Now()) {
+		respondWithJSON(w, http.StatusOK, response{
+			Active:    true,
+			Scope:     strings.Join(storedToken.Scopes, " "),
+			ClientID:  client.ID.String(),
+			Subject:   storedToken.UserID.String(),
+			ExpiresAt: storedToken.ExpiresAt.Unix(),
+			IssuedAt:  storedToken.CreatedAt.Unix(),
+		})
+		return
+	}
+
+	// anything else - including other clients' tokens - is simply inactive
+	respondWithJSON(w, http.StatusOK, response{})
+}
+
+// revokeOAuthTokenHandler - [POST /api/oauth/revoke] : revokes the session behind one of the client's tokens, per RFC 7009
+func (cfg *apiConfig) revokeOAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	client, ok := cfg.parseClientForm(w, r)
+	if !ok {
+		return
+	}
+	tokenString := r.PostForm.Get("token")
+
+	// access tokens can't be revoked on their own, so their whole session is - either kind of token ends it
+	familyID, userID := uuid.Nil, uuid.Nil
+	if token, err := auth.ParseJWT(tokenString, cfg.jwtKeys); err == nil && token.ClientID == client.ID {
+		familyID, userID = token.SessionID, token.UserID
+	} else if storedToken, err := cfg.db.GetRefreshToken(r.Context(), tokenString); err == nil &&
+		storedToken.ClientID.Valid && storedToken.ClientID.UUID == client.ID {
+		familyID, userID = storedToken.FamilyID, storedToken.UserID
+	}
+
+	if familyID != uuid.Nil {
+		err := cfg.db.RevokeTokenFamily(r.Context(), familyID)
+		if err != nil {
+			respondWithOAuthError(w, http.StatusInternalServerError, &oauthError{Code: oauthErrServerError}, err)
+			return
+		}
+
+		cfg.recordAudit(r, auditEvent{
+			actorID:    userID,
+			action:     auditTokenRevoked,
+			targetType: auditTargetTokenFamily,
+			targetID:   familyID.String(),
+			diff:       map[string]any{"client_id": client.ID},
+		})
+	}
+
+	// unknown and already revoked tokens get the same response, so the client learns nothing from it
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+const (
+	maxClientNameLength   = 100
+	maxClientRedirectURIs = 10
+)
+
+// OAuthClient is a third-party app that users can let act on their behalf. The secret of a
+// confidential client is only ever included in the response that registered it.
+type OAuthClient struct {
+	ID           uuid.UUID `json:"client_id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	Secret       string    `json:"client_secret,omitempty"`
+}
+
+// createOAuthClientHandler - [POST /api/oauth/clients] : registers a third-party app, returning its secret this once
+func (cfg *apiConfig) createOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// validate
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required", nil)
+		return
+	}
+	if utf8.RuneCountInString(params.Name) > maxClientNameLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("name must be at most %d characters", maxClientNameLength), nil)
+		return
+	}
+	if len(params.RedirectURIs) == 0 || len(params.RedirectURIs) > maxClientRedirectURIs {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("between 1 and %d redirect_uris are required", maxClientRedirectURIs), nil)
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		err = validateRedirectURI(uri)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	// confidential clients get a secret - only its hash is stored, so it can't be shown again
+	secret, secretHash := "", sql.NullString{}
+	if params.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Couldn't create client secret", err)
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := cfg.db.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         params.Name,
+		RedirectUris: params.RedirectURIs,
+		SecretHash:   secretHash,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to register client", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditOAuthClientCreated,
+		targetType: auditTargetOAuthClient,
+		targetID:   client.ID.String(),
+		diff:       map[string]any{"name": client.Name, "redirect_uris": client.RedirectUris},
+	})
+
+	// write response
+	respClient := mapOAuthClient(client)
+	respClient.Secret = secret
+	respondWithJSON(w, http.StatusCreated, respClient)
+}
+
+// getOAuthClientsHandler - [GET /api/oauth/clients] : lists the apps the user has registered, newest first
+func (cfg *apiConfig) getOAuthClientsHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	clients, err := cfg.db.ListOAuthClients(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get clients", err)
+		return
+	}
+
+	// map for correct json representation
+	respClients := make([]OAuthClient, 0, len(clients))
+	for _, client := range clients {
+		respClients = append(respClients, mapOAuthClient(client))
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respClients)
+}
+
+// getOAuthClientHandler - [GET /api/oauth/clients/{clientID}] : serves a registered app, for the consent page to show
+func (cfg *apiConfig) getOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	// unpack client id
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid client ID", err)
+		return
+	}
+
+	client, err := cfg.db.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Client does not exist", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get client", err)
+		return
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapOAuthClient(client))
+}
+
+// deleteOAuthClientHandler - [DELETE /api/oauth/clients/{clientID}] : deletes one of the user's apps, revoking everything issued to it
+func (cfg *apiConfig) deleteOAuthClientHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// unpack client id
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid client ID", err)
+		return
+	}
+
+	// only the owner can delete a client - its codes and refresh tokens go with it
+	deleted, err := cfg.db.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete client", err)
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Client does not exist", nil)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditOAuthClientDeleted,
+		targetType: auditTargetOAuthClient,
+		targetID:   clientID.String(),
+	})
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateRedirectURI checks a redirect URI a client is registering. Codes are sent to it, so it must
+// use https, unless it is on the loopback interface for an app running on the user's own machine.
+func validateRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("redirect_uri %q must be an absolute URL", uri)
+	}
+	if u.Fragment != "" {
+		return fmt.Errorf("redirect_uri %q can't have a fragment", uri)
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if host == "localhost" {
+			return nil
+		}
+		if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+			return nil
+		}
+	}
+	return fmt.Errorf("redirect_uri %q must use https, or http on localhost", uri)
+}
+
+// mapOAuthClient converts a database client into its json representation, without the secret
+func mapOAuthClient(c database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectUris,
+		Confidential: c.SecretHash.Valid,
+		CreatedAt:    c.CreatedAt,
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// page is the envelope returned by every paginated listing
+type page[T any] struct {
+	Items []T    `json:"items"`
+	Next  string `json:"next,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+}
+
+// pageCursor identifies a row in a listing ordered by (created_at, id),
+// or by (rank, created_at, id) for search results
+type pageCursor struct {
+	Rank      float32   `json:"r,omitempty"`
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+// pageParams holds the parsed `limit`, `after` and `before` query parameters
+type pageParams struct {
+	limit  int32
+	after  *pageCursor
+	before *pageCursor
+}
+
+// encodeCursor turns a cursor into an opaque, url safe string
+func encodeCursor(c pageCursor) string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	c := pageCursor{}
+	if err := json.Unmarshal(dat, &c); err != nil {
+		return nil, err
+	}
+	if c.ID == uuid.Nil {
+		return nil, errors.New("cursor is missing an id")
+	}
+	return &c, nil
+}
+
+// parsePageParams reads the pagination parameters from the request's query string
+func parsePageParams(r *http.Request) (pageParams, error) {
+	query := r.URL.Query()
+	p := pageParams{limit: defaultPageLimit}
+
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return pageParams{}, errors.New("limit must be a positive integer")
+		}
+		p.limit = int32(min(limit, maxPageLimit))
+	}
+
+	if query.Has("after") && query.Has("before") {
+		return pageParams{}, errors.New("after and before cannot be used together")
+	}
+
+	var err error
+	if s := query.Get("after"); s != "" {
+		if p.after, err = decodeCursor(s); err != nil {
+			return pageParams{}, errors.New("invalid after cursor")
+		}
+	}
+	if s := query.Get("before"); s != "" {
+		if p.before, err = decodeCursor(s); err != nil {
+			return pageParams{}, errors.New("invalid before cursor")
+		}
+	}
+
+	return p, nil
+}
+
+// pageFetcher loads up to limit rows past the cursor (nil for the start of the listing).
+// forward is true when reading in listing order, false when reading back towards the start.
+type pageFetcher[T any] func(forward bool, cursor *pageCursor, limit int32) ([]T, error)
+
+// paginate fetches a single page of a keyset listing and builds its next/prev links
+func paginate[T any](r *http.Request, p pageParams, fetch pageFetcher[T], key func(T) pageCursor) (page[T], error) {
+	forward := p.before == nil
+	cursor := p.after
+	if !forward {
+		cursor = p.before
+	}
+
+	// fetch one extra row to find out whether there is more to read
+	rows, err := fetch(forward, cursor, p.limit+1)
+	if err != nil {
+		return page[T]{}, err
+	}
+	hasMore := len(rows) > int(p.limit)
+	if hasMore {
+		rows = rows[:p.limit]
+	}
+
+	// rows read backwards come out in reverse listing order
+	if !forward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	res := page[T]{Items: rows}
+	if res.Items == nil {
+		res.Items = []T{}
+	}
+	if len(rows) == 0 {
+		return res, nil
+	}
+
+	// there is always something on the other side of the cursor we came from
+	if (forward && hasMore) || (!forward && cursor != nil) {
+		res.Next = pageLink(r, "after", key(rows[len(rows)-1]))
+	}
+	if (!forward && hasMore) || (forward && cursor != nil) {
+		res.Prev = pageLink(r, "before", key(rows[0]))
+	}
+
+	return res, nil
+}
+
+// pageLink rebuilds the request URL pointing at the page on one side of a cursor
+func pageLink(r *http.Request, direction string, c pageCursor) string {
+	query := r.URL.Query()
+	query.Del("after")
+	query.Del("before")
+	query.Set(direction, encodeCursor(c))
+
+	u := *r.URL
+	u.RawQuery = query.Encode()
+	return u.RequestURI()
+}
+
+// cursorArgs converts an optional cursor into nullable query arguments
+func cursorArgs(c *pageCursor) (sql.NullTime, uuid.NullUUID) {
+	if c == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}, uuid.NullUUID{UUID: c.ID, Valid: true}
+}
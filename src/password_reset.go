@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+	"github.com/wkeebs/chirpy/internal/mail"
+)
+
+// requestPasswordResetHandler - [POST /api/password-reset/request] : emails a one-time reset token, if the email belongs to a user
+func (cfg *apiConfig) requestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// the lookup and email happen after responding, so neither the response nor how long
+	// it takes gives away whether the email belongs to anyone
+	detached := r.WithContext(context.WithoutCancel(r.Context()))
+	go cfg.sendPasswordReset(detached, params.Email)
+
+	// respond with 204 either way
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendPasswordReset creates a reset token for the user with the given email and mails it to them.
+// It runs in the background, so failures are logged.
+func (cfg *apiConfig) sendPasswordReset(r *http.Request, email string) {
+	user, err := cfg.db.GetUserByEmail(r.Context(), email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to look up user for password reset: %s", err)
+		return
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("Failed to create password reset token: %s", err)
+		return
+	}
+	err = cfg.db.CreatePasswordResetToken(r.Context(), database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to store password reset token: %s", err)
+		return
+	}
+
+	err = cfg.mailer.Send(r.Context(), mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account. If it was you, "+
+			"use this token within the next hour to choose a new one:\n\n%s\n\n"+
+			"If it wasn't you, you can ignore this email - your password hasn't changed.\n", token),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email: %s", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		action:     auditPasswordResetRequested,
+		targetType: auditTargetUser,
+		targetID:   user.ID.String(),
+	})
+}
+
+// confirmPasswordResetHandler - [POST /api/password-reset/confirm] : sets a new password with a reset token, signing the user out everywhere and revoking their personal access tokens
+func (cfg *apiConfig) confirmPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// check the new password before the token is used up
+	err = cfg.passwords.Validate(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't hash password", err)
+		return
+	}
+
+	// using the token marks it used, so it can only ever work once
+	userID, err := cfg.db.UsePasswordResetToken(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Invalid or expired reset token", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check reset token", err)
+		return
+	}
+
+	_, err = cfg.db.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update password", err)
+		return
+	}
+
+	// any other reset emails still waiting are now stale
+	err = cfg.db.ExpireUserPasswordResetTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to expire reset tokens", err)
+		return
+	}
+
+	// whoever knew the old password may still be signed in, or have made tokens of their own
+	err = cfg.db.RevokeUserTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+	err = cfg.db.RevokeUserPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke personal access tokens", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditPasswordReset,
+		targetType: auditTargetUser,
+		targetID:   userID.String(),
+	})
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
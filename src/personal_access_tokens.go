@@ -0,0 +1,236 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+const maxTokenNameLength = 100
+
+// PersonalAccessToken is a long lived token a user made for a script or bot. The token itself
+// is only ever included in the response that created it.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+// usePersonalAccessToken looks up a personal access token, noting that it was used, and returns what it grants
+func (cfg *apiConfig) usePersonalAccessToken(r *http.Request, tokenString string) (auth.AccessToken, error) {
+	pat, err := cfg.db.UsePersonalAccessToken(r.Context(), auth.HashToken(tokenString))
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.AccessToken{}, errors.New("invalid, expired or revoked personal access token")
+	}
+	if err != nil {
+		return auth.AccessToken{}, err
+	}
+
+	scopes, err := auth.ParseScopes(pat.Scopes)
+	if err != nil {
+		return auth.AccessToken{}, err
+	}
+	return auth.AccessToken{UserID: pat.UserID, Role: auth.RoleUser, Scopes: scopes}, nil
+}
+
+// createPersonalAccessTokenHandler - [POST /api/tokens] : creates a personal access token, returning it this once
+func (cfg *apiConfig) createPersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	// check access token - only a real login can make tokens, never another token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// validate
+	params.Name = strings.TrimSpace(params.Name)
+	if params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "name is required", nil)
+		return
+	}
+	if utf8.RuneCountInString(params.Name) > maxTokenNameLength {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("name must be at most %d characters", maxTokenNameLength), nil)
+		return
+	}
+	if len(params.Scopes) == 0 {
+		respondWithError(w, http.StatusBadRequest, "at least one scope is required", nil)
+		return
+	}
+	scopes, err := auth.ParseScopes(params.Scopes)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(cfg.now()) {
+			respondWithError(w, http.StatusBadRequest, "expires_at must be in the future", nil)
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+
+	// only the hash is stored, so the token can't be shown again
+	tokenString, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create token", err)
+		return
+	}
+	pat, err := cfg.db.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(tokenString),
+		Scopes:    auth.ScopeStrings(scopes),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create token", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditPATCreated,
+		targetType: auditTargetPersonalAccessToken,
+		targetID:   pat.ID.String(),
+		diff:       map[string]any{"name": pat.Name, "scopes": pat.Scopes},
+	})
+
+	// write response
+	respPAT := mapPersonalAccessToken(pat)
+	respPAT.Token = tokenString
+	respondWithJSON(w, http.StatusCreated, respPAT)
+}
+
+// getPersonalAccessTokensHandler - [GET /api/tokens] : lists the user's personal access tokens, newest first
+func (cfg *apiConfig) getPersonalAccessTokensHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	pats, err := cfg.db.ListPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get tokens", err)
+		return
+	}
+
+	// map for correct json representation
+	respPATs := make([]PersonalAccessToken, 0, len(pats))
+	for _, pat := range pats {
+		respPATs = append(respPATs, mapPersonalAccessToken(pat))
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respPATs)
+}
+
+// revokePersonalAccessTokenHandler - [DELETE /api/tokens/{tokenID}] : revokes one of the user's personal access tokens
+func (cfg *apiConfig) revokePersonalAccessTokenHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// unpack token id
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid token ID", err)
+		return
+	}
+
+	// only the user's own tokens can be revoked
+	revoked, err := cfg.db.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke token", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Token does not exist", nil)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditPATRevoked,
+		targetType: auditTargetPersonalAccessToken,
+		targetID:   tokenID.String(),
+	})
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mapPersonalAccessToken converts a database personal access token into its json representation, without the token
+func mapPersonalAccessToken(pat database.PersonalAccessToken) PersonalAccessToken {
+	respPAT := PersonalAccessToken{
+		ID:        pat.ID,
+		Name:      pat.Name,
+		Scopes:    pat.Scopes,
+		CreatedAt: pat.CreatedAt,
+	}
+	if pat.ExpiresAt.Valid {
+		respPAT.ExpiresAt = &pat.ExpiresAt.Time
+	}
+	if pat.LastUsedAt.Valid {
+		respPAT.LastUsedAt = &pat.LastUsedAt.Time
+	}
+	return respPAT
+}
@@ -55,6 +55,14 @@ func (cfg *apiConfig) upgradeUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// upgrades come from polka, so there is no actor
+	cfg.recordAudit(r, auditEvent{
+		action:     auditPremiumUpgraded,
+		targetType: auditTargetUser,
+		targetID:   params.Data.UserID.String(),
+		diff:       map[string]any{"is_chirpy_red": auditChange{From: false, To: true}},
+	})
+
 	// success - respond with 204
 	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusNoContent)
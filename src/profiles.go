@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+const (
+	minHandleLength      = 3
+	maxHandleLength      = 15
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+)
+
+// handles are what follows the @ in a mention
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// reservedHandles can't be claimed, so they never shadow a route or impersonate the service
+var reservedHandles = map[string]bool{
+	"about":     true,
+	"admin":     true,
+	"api":       true,
+	"app":       true,
+	"chirps":    true,
+	"chirpy":    true,
+	"help":      true,
+	"login":     true,
+	"logout":    true,
+	"me":        true,
+	"mentions":  true,
+	"moderator": true,
+	"root":      true,
+	"search":    true,
+	"settings":  true,
+	"support":   true,
+	"system":    true,
+	"timeline":  true,
+	"trending":  true,
+	"users":     true,
+}
+
+// Profile is the public view of a user - it never includes their email
+type Profile struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Handle      string    `json:"handle,omitempty"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	IsPremium   bool      `json:"is_chirpy_red"`
+}
+
+// mapProfile converts a database user into its public json representation
+func mapProfile(u database.User) Profile {
+	return Profile{
+		ID:          u.ID,
+		CreatedAt:   u.CreatedAt,
+		Handle:      u.Handle.String,
+		DisplayName: u.DisplayName,
+		Bio:         u.Bio,
+		IsPremium:   u.IsPremium,
+	}
+}
+
+func validateHandle(handle string) error {
+	if len(handle) < minHandleLength || len(handle) > maxHandleLength {
+		return fmt.Errorf("handles must be between %d and %d characters", minHandleLength, maxHandleLength)
+	}
+	if !handlePattern.MatchString(handle) {
+		return errors.New("handles may only contain letters, digits and underscores")
+	}
+	if reservedHandles[strings.ToLower(handle)] {
+		return errors.New("handle is reserved")
+	}
+	return nil
+}
+
+func validateDisplayName(displayName string) error {
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return fmt.Errorf("display names must be at most %d characters", maxDisplayNameLength)
+	}
+	return nil
+}
+
+func validateBio(bio string) error {
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return fmt.Errorf("bios must be at most %d characters", maxBioLength)
+	}
+	return nil
+}
+
+// getProfileHandler - [GET /api/users/{handle}] : serves a user's public profile
+func (cfg *apiConfig) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	// handles are matched case-insensitively
+	user, err := cfg.db.GetUserByHandle(r.Context(), r.PathValue("handle"))
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapProfile(user))
+}
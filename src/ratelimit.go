@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/ratelimit"
+)
+
+// what a rate limit is counted against
+const (
+	rateLimitByIP   = "ip"
+	rateLimitByUser = "user" // the user in the access token, or the address for personal access tokens and when there isn't a valid one
+)
+
+const rateLimitSweepInterval = 5 * time.Minute
+
+// rateLimitRule limits the requests matching a route pattern
+type rateLimitRule struct {
+	pattern string
+	limit   ratelimit.Limit
+	by      string
+}
+
+// defaultRateLimits apply unless RATE_LIMITS overrides them
+var defaultRateLimits = []rateLimitRule{
+	{pattern: "POST /api/login", limit: ratelimit.Limit{Requests: 10, Period: time.Minute}, by: rateLimitByIP},
+	{pattern: "POST /api/login/mfa", limit: ratelimit.Limit{Requests: 10, Period: time.Minute}, by: rateLimitByIP},
+	{pattern: "POST /api/users", limit: ratelimit.Limit{Requests: 10, Period: time.Hour}, by: rateLimitByIP},
+	{pattern: "POST /api/password-reset/request", limit: ratelimit.Limit{Requests: 5, Period: time.Hour}, by: rateLimitByIP},
+	{pattern: "POST /api/email-verification/request", limit: ratelimit.Limit{Requests: 5, Period: time.Hour}, by: rateLimitByUser},
+	{pattern: "POST /api/oauth/token", limit: ratelimit.Limit{Requests: 30, Period: time.Minute}, by: rateLimitByIP},
+	{pattern: "POST /api/chirps", limit: ratelimit.Limit{Requests: 30, Period: time.Minute}, by: rateLimitByUser},
+	{pattern: "POST /api/chirps/{chirpID}/report", limit: ratelimit.Limit{Requests: 20, Period: time.Hour}, by: rateLimitByUser},
+}
+
+// parseRateLimits applies RATE_LIMITS overrides to a set of rules. Overrides are separated by semicolons,
+// each either "<pattern>=<requests>/<period>/<ip|user>" or "<pattern>=off", for example
+// "POST /api/chirps=60/1m/user;POST /api/users=off".
+func parseRateLimits(s string, rules []rateLimitRule) ([]rateLimitRule, error) {
+	byPattern := map[string]rateLimitRule{}
+	order := []string{}
+	for _, rule := range rules {
+		byPattern[rule.pattern] = rule
+		order = append(order, rule.pattern)
+	}
+
+	for _, override := range strings.Split(s, ";") {
+		override = strings.TrimSpace(override)
+		if override == "" {
+			continue
+		}
+		i := strings.LastIndex(override, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("rate limit %q must be <pattern>=<limit>", override)
+		}
+		pattern, value := strings.TrimSpace(override[:i]), strings.TrimSpace(override[i+1:])
+
+		if _, ok := byPattern[pattern]; !ok {
+			order = append(order, pattern)
+		}
+		if value == "off" {
+			delete(byPattern, pattern)
+			continue
+		}
+
+		parts := strings.Split(value, "/")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("rate limit %q must be <requests>/<period>/<ip|user>", value)
+		}
+		requests, err := strconv.Atoi(parts[0])
+		if err != nil || requests < 1 {
+			return nil, fmt.Errorf("rate limit %q must allow at least one request", value)
+		}
+		period, err := time.ParseDuration(parts[1])
+		if err != nil || period <= 0 {
+			return nil, fmt.Errorf("rate limit %q has an invalid period", value)
+		}
+		if parts[2] != rateLimitByIP && parts[2] != rateLimitByUser {
+			return nil, fmt.Errorf("rate limit %q must be counted by %s or %s", value, rateLimitByIP, rateLimitByUser)
+		}
+
+		byPattern[pattern] = rateLimitRule{
+			pattern: pattern,
+			limit:   ratelimit.Limit{Requests: requests, Period: period},
+			by:      parts[2],
+		}
+	}
+
+	parsed := []rateLimitRule{}
+	for _, pattern := range order {
+		if rule, ok := byPattern[pattern]; ok {
+			parsed = append(parsed, rule)
+			delete(byPattern, pattern)
+		}
+	}
+	return parsed, nil
+}
+
+// rateLimiter throttles requests with token buckets, one per rule and caller
+type rateLimiter struct {
+	store ratelimit.Store
+	// routes matches requests to rules the same way the API's mux matches them to handlers
+	routes *http.ServeMux
+	rules  map[string]rateLimitRule
+	viewer func(*http.Request) (auth.AccessToken, bool)
+	now    func() time.Time
+}
+
+func newRateLimiter(
+	store ratelimit.Store,
+	rules []rateLimitRule,
+	viewer func(*http.Request) (auth.AccessToken, bool),
+	now func() time.Time,
+) (limiter *rateLimiter, err error) {
+	limiter = &rateLimiter{
+		store:  store,
+		routes: http.NewServeMux(),
+		rules:  map[string]rateLimitRule{},
+		viewer: viewer,
+		now:    now,
+	}
+
+	// the mux panics on patterns it can't use
+	defer func() {
+		if p := recover(); p != nil {
+			limiter, err = nil, fmt.Errorf("invalid rate limit pattern: %v", p)
+		}
+	}()
+	for _, rule := range rules {
+		limiter.routes.HandleFunc(rule.pattern, func(http.ResponseWriter, *http.Request) {})
+		limiter.rules[rule.pattern] = rule
+	}
+	return limiter, nil
+}
+
+// middleware wraps a handler, turning away callers who are over the limit for the route they requested
+func (l *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := l.routes.Handler(r)
+		rule, ok := l.rules[pattern]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := rule.pattern + "|" + rateLimitByIP + ":" + clientIP(r)
+		if rule.by == rateLimitByUser {
+			if token, ok := l.viewer(r); ok {
+				key = rule.pattern + "|" + rateLimitByUser + ":" + token.UserID.String()
+			}
+		}
+
+		// a broken store shouldn't take the API down with it, so requests are let through
+		result, err := l.store.Take(r.Context(), key, rule.limit, l.now())
+		if err != nil {
+			log.Printf("Failed to check rate limit for %s: %s", rule.pattern, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(rule.limit.Requests))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+		w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", rule.limit.Requests, ceilSeconds(rule.limit.Period)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+			respondWithError(w, http.StatusTooManyRequests, "Too many requests", nil)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds formats a duration as whole seconds, rounding up so clients never retry too early
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
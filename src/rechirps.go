@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// rechirpHandler - [POST /api/chirps/{chirpID}/rechirp] : rechirps another chirp
+func (cfg *apiConfig) rechirpHandler(w http.ResponseWriter, r *http.Request) {
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+	if !cfg.requireVerifiedEmail(w, r, userID, actionRechirp) {
+		return
+	}
+
+	// unpack chirp id
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
+	}
+
+	// rechirping a rechirp amplifies the original
+	original, err := cfg.originalChirp(r, chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp does not exist", err)
+		return
+	}
+
+	// add to database - undoing a rechirp is just deleting it
+	rechirp, err := cfg.db.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:      userID,
+		RechirpOfID: uuid.NullUUID{UUID: original.ID, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Chirp has already been rechirped", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to rechirp", err)
+		return
+	}
+
+	// map for correct json representation
+	respChirps, err := cfg.mapChirps(r, []database.Chirp{rechirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to map Chirp", err)
+		return
+	}
+
+	// create response
+	respondWithJSON(w, http.StatusCreated, respChirps[0])
+}
+
+// originalChirp looks up a live chirp, following a rechirp through to the chirp it amplifies
+func (cfg *apiConfig) originalChirp(r *http.Request, chirpID uuid.UUID) (database.Chirp, error) {
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if chirp.Kind == chirpKindRechirp && chirp.RechirpOfID.Valid {
+		chirp, err = cfg.db.GetChirp(r.Context(), chirp.RechirpOfID.UUID)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	if chirp.DeletedAt.Valid {
+		return database.Chirp{}, errors.New("chirp has been deleted")
+	}
+	if chirp.ModerationStatus != moderationStatusVisible {
+		return database.Chirp{}, errors.New("chirp is held for review")
+	}
+	return chirp, nil
+}
@@ -1,17 +1,21 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
 )
 
-// refreshHandler - [POST /api/refresh] : generates a new access token
+// refreshHandler - [POST /api/refresh] : generates a new access token, and rotates the refresh token
 func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 	type response struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 
 	// this endpoint takes no body, but expects an refresh token in the auth header
@@ -21,21 +25,77 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isValid := cfg.validateRefreshToken(r, refreshTok)
-	if !isValid {
-		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", errors.New("Invalid refresh token"))
+	// look up token
+	storedToken, err := cfg.db.GetRefreshToken(r.Context(), refreshTok)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", err)
+		return
+	}
+
+	// tokens issued to an OAuth client are refreshed through /api/oauth/token, keeping their scopes
+	if storedToken.ClientID.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", errors.New("refresh token belongs to an OAuth client"))
+		return
+	}
+
+	// a token that has already been rotated should never be seen again - if it is, it has been
+	// stolen, so the whole family is revoked to lock out whoever holds the latest token
+	if storedToken.RotatedAt.Valid {
+		cfg.revokeReusedFamily(r, storedToken)
+		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", errors.New("refresh token reused"))
+		return
+	}
+
+	// check if the token has been revoked or has expired
+	if storedToken.RevokedAt.Valid || storedToken.ExpiresAt.Before(time.Now()) {
+		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", errors.New("refresh token revoked or expired"))
+		return
 	}
 
-	storedToken, err := cfg.db.GetRefreshToken(r.Context(), refreshTok)
-	
-	// create new access token for the user
 	user, err := cfg.db.GetUserByID(r.Context(), storedToken.UserID)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "User does not exist", err)
 		return
 	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is suspended", nil)
+		return
+	}
 
-	newAccessToken, err := auth.MakeJWT(user.ID, cfg.jwtSecret, time.Duration(time.Hour))
+	// retire the old token - if it was rotated since we looked it up, it was used twice at once
+	_, err = cfg.db.RotateRefreshToken(r.Context(), refreshTok)
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.revokeReusedFamily(r, storedToken)
+		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", errors.New("refresh token reused"))
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to rotate refresh token", err)
+		return
+	}
+
+	// issue its replacement in the same family
+	newRefreshTok, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create refresh token", err)
+		return
+	}
+	_, err = cfg.db.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
+		Token:       newRefreshTok,
+		UserID:      user.ID,
+		ExpiresAt:   storedToken.ExpiresAt,
+		FamilyID:    storedToken.FamilyID,
+		ParentToken: sql.NullString{String: refreshTok, Valid: true},
+		UserAgent:   r.UserAgent(),
+		Ip:          clientIP(r),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't store refresh token", err)
+		return
+	}
+
+	// create new access token for the user
+	newAccessToken, err := auth.MakeJWT(user.ID, auth.Role(user.Role), cfg.jwtKeys, time.Duration(time.Hour))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to create new access token", err)
 		return
@@ -43,11 +103,32 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 
 	// success!
 	respondWithJSON(w, http.StatusOK, response{
-		Token: newAccessToken,
+		Token:        newAccessToken,
+		RefreshToken: newRefreshTok,
+	})
+}
+
+// revokeReusedFamily revokes every token descended from the same login as a reused token, and records the reuse
+func (cfg *apiConfig) revokeReusedFamily(r *http.Request, token database.RefreshToken) {
+	log.Printf("Refresh token reuse detected for user %s, revoking token family %s", token.UserID, token.FamilyID)
+
+	err := cfg.db.RevokeTokenFamily(r.Context(), token.FamilyID)
+	if err != nil {
+		log.Printf("Failed to revoke token family %s: %s", token.FamilyID, err)
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		action:     auditTokenReused,
+		targetType: auditTargetTokenFamily,
+		targetID:   token.FamilyID.String(),
+		diff: map[string]any{
+			"user_id": token.UserID,
+			"token":   tokenFingerprint(token.Token),
+		},
 	})
 }
 
-// revokeHandler - [POST /api/revoke] : revokes a user's refresh token
+// revokeHandler - [POST /api/revoke] : revokes a user's refresh token, along with the rest of its family
 func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
 	// this endpoint takes no body, but expects an refresh token in the auth header
 	refreshTok, err := auth.GetBearerToken(r.Header)
@@ -57,40 +138,29 @@ func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// look up token
-	_, err = cfg.db.GetRefreshToken(r.Context(), refreshTok)
+	storedToken, err := cfg.db.GetRefreshToken(r.Context(), refreshTok)
 	if err != nil {
 		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token", err)
 		return
 	}
 
-	// revoke token
-	_, err = cfg.db.RevokeToken(r.Context(), refreshTok)
+	// revoke the token's family - older tokens in it have already been rotated out,
+	// and newer ones could only have come from this token
+	err = cfg.db.RevokeTokenFamily(r.Context(), storedToken.FamilyID)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Failed to revoke token", err)
 		return
 	}
 
+	cfg.recordAudit(r, auditEvent{
+		actorID:    storedToken.UserID,
+		action:     auditTokenRevoked,
+		targetType: auditTargetRefreshToken,
+		targetID:   tokenFingerprint(refreshTok),
+		diff:       map[string]any{"family_id": storedToken.FamilyID},
+	})
+
 	// success - respond with 204
 	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
 	w.WriteHeader(http.StatusNoContent)
 }
-
-func (cfg *apiConfig) validateRefreshToken(r *http.Request, token string) bool {
-	// look up token
-	storedToken, err := cfg.db.GetRefreshToken(r.Context(), token)
-	if err != nil {
-		return false
-	}
-
-	// check if the token has been revoked
-	if storedToken.RevokedAt.Valid {
-		return false
-	}
-
-	// check if the token has expired
-	if storedToken.ExpiresAt.Before(time.Now()) {
-		return false
-	}
-
-	return true
-}
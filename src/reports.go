@@ -0,0 +1,363 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// reasons a user can give when reporting a chirp
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"misinformation": true,
+	"other":          true,
+}
+
+// chirps held by the moderation filters are queued for review with this reason
+const reportReasonFlagged = "flagged"
+
+// report statuses - resolving a report records what the moderator did about it
+const (
+	reportStatusOpen      = "open"
+	reportStatusDismissed = "dismissed"
+	reportStatusHidden    = "hidden"
+	reportStatusSuspended = "suspended"
+)
+
+// actions a moderator can take on a report, and the status they leave it in
+var reportActions = map[string]string{
+	"dismiss": reportStatusDismissed,
+	"hide":    reportStatusHidden,
+	"suspend": reportStatusSuspended,
+}
+
+type Report struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	ChirpID    uuid.UUID  `json:"chirp_id"`
+	ReporterID *uuid.UUID `json:"reporter_id,omitempty"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	Chirp      *Chirp     `json:"chirp,omitempty"`
+}
+
+// reportChirpHandler - [POST /api/chirps/{chirpID}/report] : reports a chirp to the moderators
+func (cfg *apiConfig) reportChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+	if !cfg.requireVerifiedEmail(w, r, userID, actionReport) {
+		return
+	}
+
+	// unpack chirp id
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	// validate report
+	if !reportReasons[params.Reason] {
+		err = errors.New("reason must be spam, harassment, hate, violence, misinformation or other")
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	const maxDetailsLength = 500
+	if len(params.Details) > maxDetailsLength {
+		respondWithError(w, http.StatusBadRequest, "details must be at most 500 characters", nil)
+		return
+	}
+
+	// check the chirp exists and can be seen by the reporter
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid || !cfg.canSeeChirp(r, chirp) {
+		respondWithError(w, http.StatusNotFound, "Chirp does not exist", err)
+		return
+	}
+
+	// add to database - each user can only report a chirp once
+	report, err := cfg.db.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirpID,
+		ReporterID: uuid.NullUUID{UUID: userID, Valid: true},
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusConflict, "Chirp has already been reported", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to report Chirp", err)
+		return
+	}
+
+	// create response
+	respondWithJSON(w, http.StatusCreated, mapReport(report))
+}
+
+// getReportsHandler - [GET /admin/reports] : serves a page of the moderation queue, oldest first
+func (cfg *apiConfig) getReportsHandler(w http.ResponseWriter, r *http.Request) {
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// the queue shows open reports unless asked otherwise
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = reportStatusOpen
+	case reportStatusOpen, reportStatusDismissed, reportStatusHidden, reportStatusSuspended:
+	default:
+		respondWithError(w, http.StatusBadRequest, "status must be open, dismissed, hidden or suspended", nil)
+		return
+	}
+
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]database.Report, error) {
+		createdAt, id := cursorArgs(cursor)
+		if forward {
+			return cfg.db.ListReportsAsc(r.Context(), database.ListReportsAscParams{
+				Status:          status,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		}
+		return cfg.db.ListReportsDesc(r.Context(), database.ListReportsDescParams{
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+	}
+
+	reports, err := paginate(r, pageParams, fetch, func(rep database.Report) pageCursor {
+		return pageCursor{CreatedAt: rep.CreatedAt, ID: rep.ID}
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get reports", err)
+		return
+	}
+
+	// embed the reported chirps so moderators can see what they are judging
+	chirpIDs := make([]uuid.UUID, 0, len(reports.Items))
+	for _, rep := range reports.Items {
+		chirpIDs = append(chirpIDs, rep.ChirpID)
+	}
+	chirps, err := cfg.db.GetChirpsByIDs(r.Context(), chirpIDs)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get Chirps", err)
+		return
+	}
+	respChirps, err := cfg.mapChirps(r, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to map Chirps", err)
+		return
+	}
+	byID := make(map[uuid.UUID]*Chirp, len(respChirps))
+	for i := range respChirps {
+		byID[respChirps[i].ID] = &respChirps[i]
+	}
+
+	// map for correct json representation
+	respReports := page[Report]{
+		Items: make([]Report, 0, len(reports.Items)),
+		Next:  reports.Next,
+		Prev:  reports.Prev,
+	}
+	for _, rep := range reports.Items {
+		report := mapReport(rep)
+		report.Chirp = byID[rep.ChirpID]
+		respReports.Items = append(respReports.Items, report)
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respReports)
+}
+
+// resolveReportHandler - [POST /admin/reports/{reportID}/resolve] : dismisses a report, hides the chirp, or suspends its author
+func (cfg *apiConfig) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action string `json:"action"`
+	}
+
+	// unpack report id
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid report ID", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+	status, ok := reportActions[params.Action]
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "action must be dismiss, hide or suspend", nil)
+		return
+	}
+
+	// get report
+	report, err := cfg.db.GetReport(r.Context(), reportID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Report does not exist", err)
+		return
+	}
+	if report.Status != reportStatusOpen {
+		respondWithError(w, http.StatusConflict, "Report has already been resolved", nil)
+		return
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), report.ChirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Chirp does not exist", err)
+		return
+	}
+
+	// moderators can only suspend users below them, so they can't lock out each other or an admin
+	if status == reportStatusSuspended {
+		author, err := cfg.db.GetUserByID(r.Context(), chirp.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to find author", err)
+			return
+		}
+		token, _ := cfg.viewer(r)
+		if auth.Role(author.Role).Has(token.Role) {
+			respondWithError(w, http.StatusForbidden, "Can't suspend a user with the same or a higher role", nil)
+			return
+		}
+	}
+
+	switch status {
+	case reportStatusDismissed:
+		// dismissing a report on a held chirp lets it through
+		if chirp.ModerationStatus == moderationStatusHeld {
+			chirp, err = cfg.db.SetChirpModerationStatus(r.Context(), database.SetChirpModerationStatusParams{
+				ID:               chirp.ID,
+				ModerationStatus: moderationStatusVisible,
+			})
+			if err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Failed to release Chirp", err)
+				return
+			}
+			cfg.indexChirp(r, chirp)
+		}
+	case reportStatusHidden, reportStatusSuspended:
+		_, err = cfg.db.SetChirpModerationStatus(r.Context(), database.SetChirpModerationStatusParams{
+			ID:               chirp.ID,
+			ModerationStatus: moderationStatusHidden,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to hide Chirp", err)
+			return
+		}
+	}
+
+	// suspended users can no longer log in, are signed out everywhere, and lose their personal access tokens
+	if status == reportStatusSuspended {
+		_, err = cfg.db.SuspendUser(r.Context(), chirp.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to suspend user", err)
+			return
+		}
+		err = cfg.db.RevokeUserTokens(r.Context(), chirp.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke tokens", err)
+			return
+		}
+		err = cfg.db.RevokeUserPersonalAccessTokens(r.Context(), chirp.UserID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke tokens", err)
+			return
+		}
+
+		cfg.recordAudit(r, auditEvent{
+			actorID:    cfg.viewerID(r),
+			action:     auditUserSuspended,
+			targetType: auditTargetUser,
+			targetID:   chirp.UserID.String(),
+			diff:       map[string]any{"report_id": report.ID},
+		})
+	}
+
+	// every open report on the chirp is settled by the same decision
+	err = cfg.db.ResolveChirpReports(r.Context(), database.ResolveChirpReportsParams{
+		ChirpID: chirp.ID,
+		Status:  status,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to resolve reports", err)
+		return
+	}
+
+	report, err = cfg.db.GetReport(r.Context(), reportID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get report", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    cfg.viewerID(r),
+		action:     auditReportResolved,
+		targetType: auditTargetReport,
+		targetID:   report.ID.String(),
+		diff: map[string]any{
+			"status":   auditChange{From: reportStatusOpen, To: report.Status},
+			"chirp_id": chirp.ID,
+		},
+	})
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapReport(report))
+}
+
+// mapReport converts a database report into its json representation
+func mapReport(rep database.Report) Report {
+	report := Report{
+		ID:        rep.ID,
+		CreatedAt: rep.CreatedAt,
+		UpdatedAt: rep.UpdatedAt,
+		ChirpID:   rep.ChirpID,
+		Reason:    rep.Reason,
+		Details:   rep.Details,
+		Status:    rep.Status,
+	}
+	if rep.ReporterID.Valid {
+		report.ReporterID = &rep.ReporterID.UUID
+	}
+	if rep.ResolvedAt.Valid {
+		report.ResolvedAt = &rep.ResolvedAt.Time
+	}
+	return report
+}
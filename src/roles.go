@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+type contextKey string
+
+// accessTokenKey holds the caller's validated access token in the context of requests that went through
+// requireRole or authenticate
+const accessTokenKey contextKey = "access_token"
+
+// requireRole wraps a handler so it can only be reached with an access token carrying at least the given role.
+// Roles are read from the token, so a role change takes effect once the user's access token is next refreshed.
+func (cfg *apiConfig) requireRole(role auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// check access token
+		tokenString, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+			return
+		}
+
+		token, err := auth.ParseJWT(tokenString, cfg.jwtKeys)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		// check role
+		if !token.Role.Has(role) {
+			respondWithError(w, http.StatusForbidden, "Forbidden", nil)
+			return
+		}
+		if !cfg.checkNotSuspended(w, r, token.UserID) {
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), accessTokenKey, token)))
+	}
+}
+
+// authenticate wraps a handler so it can only be reached by a signed in user, with either an access token from
+// logging in, or a personal access token or OAuth client's token granted the given scope. Login tokens can do
+// anything their user can, and the others never carry more than RoleUser, so the admin API stays out of reach.
+func (cfg *apiConfig) authenticate(scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// check access token
+		tokenString, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+			return
+		}
+
+		var token auth.AccessToken
+		if auth.IsPersonalAccessToken(tokenString) {
+			token, err = cfg.usePersonalAccessToken(r, tokenString)
+		} else {
+			token, err = auth.ParseJWT(tokenString, cfg.jwtKeys)
+		}
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+			return
+		}
+
+		active, err := cfg.sessionActive(r, token)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to check session", err)
+			return
+		}
+		if !active {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized", errors.New("session revoked"))
+			return
+		}
+
+		// check scope
+		if !token.Allows(scope) {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("Token is missing the %s scope", scope), nil)
+			return
+		}
+		if !cfg.checkNotSuspended(w, r, token.UserID) {
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), accessTokenKey, token)))
+	}
+}
+
+// sessionActive reports whether the session an access token was issued from is still active. Tokens issued to
+// OAuth clients stop working as soon as their session is revoked - login tokens carry no session, so always pass.
+func (cfg *apiConfig) sessionActive(r *http.Request, token auth.AccessToken) (bool, error) {
+	if token.SessionID == uuid.Nil {
+		return true, nil
+	}
+	return cfg.db.TokenFamilyActive(r.Context(), token.SessionID)
+}
+
+// checkNotSuspended responds with an error, returning false, unless the user exists and isn't suspended. Access
+// tokens outlive a suspension, so it is checked on every request rather than only when signing in.
+func (cfg *apiConfig) checkNotSuspended(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return false
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to find user", err)
+		return false
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is suspended", nil)
+		return false
+	}
+	return true
+}
+
+// viewer returns the access token of the user making the request, if there is a valid one.
+// Public endpoints use it to personalise their responses, so a bad token is treated as no token.
+func (cfg *apiConfig) viewer(r *http.Request) (auth.AccessToken, bool) {
+	if token, ok := r.Context().Value(accessTokenKey).(auth.AccessToken); ok {
+		return token, true
+	}
+
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return auth.AccessToken{}, false
+	}
+	token, err := auth.ParseJWT(tokenString, cfg.jwtKeys)
+	if err != nil {
+		return auth.AccessToken{}, false
+	}
+	if active, err := cfg.sessionActive(r, token); err != nil || !active {
+		return auth.AccessToken{}, false
+	}
+	return token, true
+}
+
+// viewerHasRole reports whether the user making the request has at least the given role
+func (cfg *apiConfig) viewerHasRole(r *http.Request, role auth.Role) bool {
+	token, ok := cfg.viewer(r)
+	return ok && token.Role.Has(role)
+}
+
+// setUserRoleHandler - [PUT /admin/users/{userID}/role] : changes a user's role
+func (cfg *apiConfig) setUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	// unpack user id
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid user ID", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// admins can't demote themselves, so there is always at least one left
+	token, _ := cfg.viewer(r)
+	if userID == token.UserID && role != auth.RoleAdmin {
+		respondWithError(w, http.StatusConflict, "Admins can't demote themselves", nil)
+		return
+	}
+
+	// keep the old role for the audit log
+	oldUser, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+
+	// update role
+	user, err := cfg.db.SetUserRole(r.Context(), database.SetUserRoleParams{
+		ID:   userID,
+		Role: string(role),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to update role", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    token.UserID,
+		action:     auditRoleChanged,
+		targetType: auditTargetUser,
+		targetID:   user.ID.String(),
+		diff:       map[string]any{"role": auditChange{From: oldUser.Role, To: user.Role}},
+	})
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapUser(user))
+}
+
+// promoteUser gives the user with the given email a role. It backs the -promote flag, which is
+// how the first admin is made - everyone after that can be promoted through the admin API.
+func promoteUser(ctx context.Context, db *database.Queries, email string, roleName string) error {
+	role, err := auth.ParseRole(roleName)
+	if err != nil {
+		return err
+	}
+
+	user, err := db.SetUserRoleByEmail(ctx, database.SetUserRoleByEmailParams{
+		Email: email,
+		Role:  string(role),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user with email %s", email)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s (%s) is now %s\n", user.Email, user.ID, user.Role)
+	return nil
+}
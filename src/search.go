@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// searchEntry is a chirp matching a search, with its relevance and highlighted body
+type searchEntry struct {
+	chirp    database.Chirp
+	rank     float32
+	headline string
+}
+
+func searchCursor(s searchEntry) pageCursor {
+	return pageCursor{Rank: s.rank, CreatedAt: s.chirp.CreatedAt, ID: s.chirp.ID}
+}
+
+// searchChirpsHandler - [GET /api/chirps/search] : serves chirps matching a query, most relevant first
+func (cfg *apiConfig) searchChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	type result struct {
+		Chirp
+		Headline string `json:"headline"`
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing search query", nil)
+		return
+	}
+
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// results are ranked by relevance then recency, so reading forward means descending order
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]searchEntry, error) {
+		createdAt, id := cursorArgs(cursor)
+		var rank sql.NullFloat64
+		if cursor != nil {
+			rank = sql.NullFloat64{Float64: float64(cursor.Rank), Valid: true}
+		}
+
+		var entries []searchEntry
+		if forward {
+			rows, err := cfg.db.SearchChirpsDesc(r.Context(), database.SearchChirpsDescParams{
+				Query:           query,
+				CursorRank:      rank,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+			for _, row := range rows {
+				entries = append(entries, searchEntry{chirp: row.Chirp, rank: row.Rank, headline: row.Headline})
+			}
+			return entries, err
+		}
+		rows, err := cfg.db.SearchChirpsAsc(r.Context(), database.SearchChirpsAscParams{
+			Query:           query,
+			CursorRank:      rank,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+		for _, row := range rows {
+			entries = append(entries, searchEntry{chirp: row.Chirp, rank: row.Rank, headline: row.Headline})
+		}
+		return entries, err
+	}
+
+	entries, err := paginate(r, pageParams, fetch, searchCursor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to search Chirps", err)
+		return
+	}
+
+	// map for correct json representation
+	chirps := make([]database.Chirp, 0, len(entries.Items))
+	for _, e := range entries.Items {
+		chirps = append(chirps, e.chirp)
+	}
+	respChirps, err := cfg.mapChirps(r, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to map Chirps", err)
+		return
+	}
+	results := page[result]{
+		Items: make([]result, 0, len(entries.Items)),
+		Next:  entries.Next,
+		Prev:  entries.Prev,
+	}
+	for i, e := range entries.Items {
+		results.Items = append(results.Items, result{Chirp: respChirps[i], Headline: e.headline})
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, results)
+}
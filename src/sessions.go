@@ -0,0 +1,154 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// Session is a login, or an OAuth client's grant, identified by its refresh token family. The device
+// details are those of the last request that used it.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	// ClientID is the OAuth client the session was granted to, if it wasn't a login
+	ClientID *uuid.UUID `json:"client_id,omitempty"`
+}
+
+// getSessionsHandler - [GET /api/sessions] : lists the user's active sessions, most recently used first
+func (cfg *apiConfig) getSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	sessions, err := cfg.db.ListUserSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get sessions", err)
+		return
+	}
+
+	// map for correct json representation
+	respSessions := make([]Session, 0, len(sessions))
+	for _, s := range sessions {
+		respSessions = append(respSessions, mapSession(s))
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respSessions)
+}
+
+// revokeSessionHandler - [DELETE /api/sessions/{sessionID}] : signs one of the user's sessions out
+func (cfg *apiConfig) revokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// unpack session id
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid session ID", err)
+		return
+	}
+
+	// only the user's own sessions can be revoked
+	revoked, err := cfg.db.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke session", err)
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "Session does not exist", nil)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditSessionRevoked,
+		targetType: auditTargetTokenFamily,
+		targetID:   sessionID.String(),
+	})
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeAllSessionsHandler - [POST /api/sessions/revoke-all] : signs the user out everywhere
+func (cfg *apiConfig) revokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	err = cfg.db.RevokeUserTokens(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditSessionsRevokedAll,
+		targetType: auditTargetUser,
+		targetID:   userID.String(),
+	})
+
+	// success - respond with 204
+	w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mapSession converts a database session into its json representation
+func mapSession(s database.ListUserSessionsRow) Session {
+	session := Session{
+		ID:         s.FamilyID,
+		SignedInAt: s.SignedInAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		UserAgent:  s.UserAgent,
+		IP:         s.Ip,
+	}
+	if s.ClientID.Valid {
+		session.ClientID = &s.ClientID.UUID
+	}
+	return session
+}
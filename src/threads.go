@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// getThreadHandler - [GET /api/chirps/{chirpID}/thread] : serves a chirp with its ancestors and a page of its replies
+func (cfg *apiConfig) getThreadHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Ancestors   []Chirp     `json:"ancestors"`
+		Chirp       Chirp       `json:"chirp"`
+		Descendants page[Chirp] `json:"descendants"`
+	}
+
+	// unpack chirp id
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid Chirp ID", err)
+		return
+	}
+
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// get chirp - tombstones are still served so the thread can be followed through them
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "Failed to get Chirp", err)
+		return
+	}
+	if !cfg.canSeeChirp(r, chirp) {
+		respondWithError(w, http.StatusNotFound, "Failed to get Chirp", nil)
+		return
+	}
+
+	// walk up to the root of the thread
+	allAncestors, err := cfg.db.GetChirpAncestors(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get ancestors", err)
+		return
+	}
+
+	// ancestors taken down by a moderator drop out, and their replies' in_reply_to dangles
+	ancestors := make([]database.Chirp, 0, len(allAncestors))
+	for _, a := range allAncestors {
+		if cfg.canSeeChirp(r, a) {
+			ancestors = append(ancestors, a)
+		}
+	}
+
+	// replies at every depth, oldest first - each one's in_reply_to rebuilds the tree
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(cursor)
+		if forward {
+			return cfg.db.ListChirpDescendantsAsc(r.Context(), database.ListChirpDescendantsAscParams{
+				ChirpID:         chirpID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		}
+		return cfg.db.ListChirpDescendantsDesc(r.Context(), database.ListChirpDescendantsDescParams{
+			ChirpID:         chirpID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+	}
+
+	descendants, err := paginate(r, pageParams, fetch, chirpCursor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get replies", err)
+		return
+	}
+
+	// map for correct json representation, looking up likes for the whole thread at once
+	chirps := make([]database.Chirp, 0, len(ancestors)+1+len(descendants.Items))
+	chirps = append(chirps, ancestors...)
+	chirps = append(chirps, chirp)
+	chirps = append(chirps, descendants.Items...)
+	respChirps, err := cfg.mapChirps(r, chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get likes", err)
+		return
+	}
+	resp := response{
+		Ancestors: respChirps[:len(ancestors)],
+		Chirp:     respChirps[len(ancestors)],
+		Descendants: page[Chirp]{
+			Items: respChirps[len(ancestors)+1:],
+			Next:  descendants.Next,
+			Prev:  descendants.Prev,
+		},
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, resp)
+}
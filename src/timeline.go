@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+// timelineHandler - [GET /api/timeline] : serves chirps from the users the caller follows, newest first
+func (cfg *apiConfig) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
+
+	pageParams, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// the timeline is newest first, so reading forward means descending order
+	fetch := func(forward bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := cursorArgs(cursor)
+		if forward {
+			return cfg.db.ListTimelineDesc(r.Context(), database.ListTimelineDescParams{
+				UserID:          userID,
+				CursorCreatedAt: createdAt,
+				CursorID:        id,
+				RowLimit:        limit,
+			})
+		}
+		return cfg.db.ListTimelineAsc(r.Context(), database.ListTimelineAscParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			RowLimit:        limit,
+		})
+	}
+
+	chirps, err := paginate(r, pageParams, fetch, chirpCursor)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get timeline", err)
+		return
+	}
+
+	// map for correct json representation
+	items, err := cfg.mapChirps(r, chirps.Items)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to get likes", err)
+		return
+	}
+	respChirps := page[Chirp]{
+		Items: items,
+		Next:  chirps.Next,
+		Prev:  chirps.Prev,
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, respChirps)
+}
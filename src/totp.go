@@ -0,0 +1,361 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/wkeebs/chirpy/internal/auth"
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+const (
+	totpIssuer = "Chirpy"
+	// mfaChallengeExpiry is how long a user has to enter their code after their password
+	mfaChallengeExpiry = 5 * time.Minute
+	recoveryCodeCount  = 10
+)
+
+// startTOTPHandler - [POST /api/users/me/totp] : starts two-factor enrolment, returning a new secret to add to an authenticator app
+func (cfg *apiConfig) startTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret string `json:"secret"`
+		URI    string `json:"otpauth_uri"`
+	}
+
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+
+	// starting again replaces any unconfirmed secret
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't create TOTP secret", err)
+		return
+	}
+	_, err = cfg.db.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		ID:         userID,
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to store TOTP secret", err)
+		return
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, response{
+		Secret: secret,
+		URI:    auth.TOTPURI(secret, totpIssuer, user.Email),
+	})
+}
+
+// confirmTOTPHandler - [POST /api/users/me/totp/confirm] : turns two-factor auth on with a first code, returning the recovery codes
+func (cfg *apiConfig) confirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+	if user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled", nil)
+		return
+	}
+	if !user.TotpSecret.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor enrolment has not been started", nil)
+		return
+	}
+
+	// the first code proves the authenticator was set up correctly
+	counter, err := auth.ValidateTOTP(user.TotpSecret.String, params.Code, cfg.now())
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid code", err)
+		return
+	}
+
+	_, err = cfg.db.EnableTOTP(r.Context(), database.EnableTOTPParams{
+		ID:              userID,
+		TotpLastCounter: sql.NullInt64{Int64: counter, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to enable two-factor authentication", err)
+		return
+	}
+
+	codes, err := cfg.replaceRecoveryCodes(r, userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to create recovery codes", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditMFAEnabled,
+		targetType: auditTargetUser,
+		targetID:   userID.String(),
+	})
+
+	// recovery codes are only stored hashed, so this is the only time they are shown
+	respondWithJSON(w, http.StatusOK, response{RecoveryCodes: codes})
+}
+
+// disableTOTPHandler - [POST /api/users/me/totp/disable] : turns two-factor auth off, which requires the user's password
+func (cfg *apiConfig) disableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+	}
+
+	// check access token
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
+		return
+	}
+
+	// unpack user ID
+	userID, err := auth.ValidateJWT(token, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
+		return
+	}
+
+	// decode request
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
+		return
+	}
+
+	// an access token alone isn't enough to turn off a second factor
+	err = auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+		return
+	}
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is not enabled", nil)
+		return
+	}
+
+	user, err = cfg.db.DisableTOTP(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to disable two-factor authentication", err)
+		return
+	}
+	err = cfg.db.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to delete recovery codes", err)
+		return
+	}
+
+	cfg.recordAudit(r, auditEvent{
+		actorID:    userID,
+		action:     auditMFADisabled,
+		targetType: auditTargetUser,
+		targetID:   userID.String(),
+	})
+
+	// write response
+	respondWithJSON(w, http.StatusOK, mapUser(user))
+}
+
+// loginMFAHandler - [POST /api/login/mfa] : completes a login with a TOTP code or a recovery code
+func (cfg *apiConfig) loginMFAHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MFAToken     string `json:"mfa_token"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	// decode payload
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Invalid payload", err)
+		return
+	}
+
+	// the challenge proves the password was right
+	userID, err := auth.ValidateMFAToken(params.MFAToken, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", err)
+		return
+	}
+
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "User does not exist", err)
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Account is suspended", nil)
+		return
+	}
+	// two-factor auth may have been turned off since the challenge was issued
+	if !user.TotpEnabledAt.Valid {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or expired MFA token", errors.New("two-factor authentication not enabled"))
+		return
+	}
+
+	// codes are guessable too, so they are throttled along with passwords
+	wait, err := cfg.ipRetryAfter(r)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to check login attempts", err)
+		return
+	}
+	if wait > 0 {
+		respondTooManyLogins(w, wait)
+		return
+	}
+	if cfg.accountLocked(user) {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect code", errors.New("account locked"))
+		return
+	}
+
+	err = cfg.checkSecondFactor(r, user, params.Code, params.RecoveryCode)
+	if err != nil {
+		cfg.recordIPFailure(r)
+		cfg.recordAccountFailure(r, user)
+		cfg.recordAudit(r, auditEvent{
+			action:     auditLoginFailed,
+			targetType: auditTargetUser,
+			targetID:   user.ID.String(),
+			diff:       map[string]any{"email": user.Email, "reason": "incorrect code"},
+		})
+		respondWithError(w, http.StatusUnauthorized, "Incorrect code", err)
+		return
+	}
+
+	cfg.completeLogin(w, r, user)
+}
+
+// checkSecondFactor accepts a TOTP code that hasn't been used before, or an unused recovery code
+func (cfg *apiConfig) checkSecondFactor(r *http.Request, user database.User, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		used, err := cfg.db.UseRecoveryCode(r.Context(), database.UseRecoveryCodeParams{
+			UserID:   user.ID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(recoveryCode)),
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return errors.New("recovery code invalid or already used")
+		}
+
+		cfg.recordAudit(r, auditEvent{
+			actorID:    user.ID,
+			action:     auditRecoveryCodeUsed,
+			targetType: auditTargetUser,
+			targetID:   user.ID.String(),
+		})
+		return nil
+	}
+
+	// a code is only good once, even within its window
+	lastCounter := int64(-1)
+	if user.TotpLastCounter.Valid {
+		lastCounter = user.TotpLastCounter.Int64
+	}
+	counter, err := auth.ValidateTOTPAfter(user.TotpSecret.String, code, cfg.now(), lastCounter)
+	if err != nil {
+		return err
+	}
+
+	// checked again as it is stored, in case the same code arrived twice at once
+	used, err := cfg.db.UseTOTPCounter(r.Context(), database.UseTOTPCounterParams{
+		ID:      user.ID,
+		Counter: counter,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return auth.ErrReusedTOTP
+	}
+	return nil
+}
+
+// replaceRecoveryCodes generates a fresh set of recovery codes, invalidating the old ones
+func (cfg *apiConfig) replaceRecoveryCodes(r *http.Request, userID uuid.UUID) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.db.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err = cfg.db.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/wkeebs/chirpy/internal/database"
+)
+
+const (
+	trendingRefreshInterval = time.Minute
+	trendingTagLimit        = 10
+)
+
+// trending windows that can be asked for, keyed by their query parameter
+var trendingWindows = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+}
+
+type TrendingTag struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// trendingAggregator periodically recomputes the top hashtags for every window,
+// so requests only ever read the latest snapshot
+type trendingAggregator struct {
+	db *database.Queries
+
+	mu        sync.RWMutex
+	tags      map[string][]TrendingTag
+	updatedAt time.Time
+}
+
+func newTrendingAggregator(db *database.Queries) *trendingAggregator {
+	return &trendingAggregator{
+		db:   db,
+		tags: map[string][]TrendingTag{},
+	}
+}
+
+// run refreshes the snapshot straight away, then on every tick until the context is done
+func (t *trendingAggregator) run(ctx context.Context) {
+	ticker := time.NewTicker(trendingRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		t.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (t *trendingAggregator) refresh(ctx context.Context) {
+	now := time.Now().UTC()
+	tags := make(map[string][]TrendingTag, len(trendingWindows))
+	for window, length := range trendingWindows {
+		rows, err := t.db.GetTrendingHashtags(ctx, database.GetTrendingHashtagsParams{
+			Since:    now.Add(-length),
+			RowLimit: trendingTagLimit,
+		})
+		if err != nil {
+			// keep serving the last good snapshot
+			log.Printf("Failed to refresh trending hashtags: %s", err)
+			return
+		}
+		tags[window] = make([]TrendingTag, 0, len(rows))
+		for _, row := range rows {
+			tags[window] = append(tags[window], TrendingTag{Tag: row.Tag, Count: row.Uses})
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tags = tags
+	t.updatedAt = now
+}
+
+// snapshot returns the latest top hashtags for a window and when they were computed
+func (t *trendingAggregator) snapshot(window string) ([]TrendingTag, time.Time) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.tags[window], t.updatedAt
+}
+
+// trendingHandler - [GET /api/trending] : serves the top hashtags over a sliding window
+func (cfg *apiConfig) trendingHandler(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Window    string        `json:"window"`
+		UpdatedAt time.Time     `json:"updated_at"`
+		Tags      []TrendingTag `json:"tags"`
+	}
+
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = "hour"
+	}
+	if _, ok := trendingWindows[window]; !ok {
+		respondWithError(w, http.StatusBadRequest, "window must be hour or day", nil)
+		return
+	}
+
+	tags, updatedAt := cfg.trending.snapshot(window)
+	if tags == nil {
+		tags = []TrendingTag{}
+	}
+
+	// write response
+	respondWithJSON(w, http.StatusOK, response{
+		Window:    window,
+		UpdatedAt: updatedAt,
+		Tags:      tags,
+	})
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/lib/pq"
 	"github.com/wkeebs/chirpy/internal/auth"
 	"github.com/wkeebs/chirpy/internal/database"
 )
 
+// defaultMinPasswordLength is the shortest password allowed, unless PASSWORD_MIN_LENGTH says otherwise
+const defaultMinPasswordLength = 8
+
 func (cfg *apiConfig) getAllUsersHandler(w http.ResponseWriter, r *http.Request) {
 	users, err := cfg.db.GetAllUsers(r.Context())
 	if err != nil {
@@ -15,16 +21,10 @@ func (cfg *apiConfig) getAllUsersHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// map for correct json representation
-	var respUsers []User
+	// map for correct json representation - emails are private, so only public profiles are listed
+	respUsers := make([]Profile, 0, len(users))
 	for _, u := range users {
-		respUsers = append(respUsers, User{
-			ID:        u.ID,
-			CreatedAt: u.CreatedAt,
-			UpdatedAt: u.UpdatedAt,
-			Email:     u.Email,
-			IsPremium: u.IsPremium,
-		})
+		respUsers = append(respUsers, mapProfile(u))
 	}
 
 	// write response
@@ -35,6 +35,7 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 	type parameters struct {
 		Password string `json:"password"`
 		Email    string `json:"email"`
+		Handle   string `json:"handle"`
 	}
 
 	// decode request
@@ -46,6 +47,28 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	err = validateEmail(params.Email)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	err = cfg.passwords.Validate(params.Password)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// handles are optional when signing up
+	var handle sql.NullString
+	if params.Handle != "" {
+		err = validateHandle(params.Handle)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		handle = sql.NullString{String: params.Handle, Valid: true}
+	}
+
 	// hash password
 	hashedPassword, err := auth.HashPassword(params.Password)
 	if err != nil {
@@ -57,20 +80,22 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 	user, err := cfg.db.CreateUser(r.Context(), database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashedPassword,
+		Handle:         handle,
 	})
+	if isUniqueViolation(err, "users_handle_idx") {
+		respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Failed to create User", nil)
 		return
 	}
 
+	// the address isn't trusted until the user follows the emailed token
+	cfg.sendVerificationEmailInBackground(r, user.ID, user.Email)
+
 	// map from databaser user struct
-	respUser := User{
-		ID:        user.ID,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Email:     user.Email,
-		IsPremium: user.IsPremium,
-	}
+	respUser := mapUser(user)
 
 	// construct response
 	respondWithJSON(w, http.StatusCreated, respUser)
@@ -78,60 +103,212 @@ func (cfg *apiConfig) createUserHandler(w http.ResponseWriter, r *http.Request)
 
 func (cfg *apiConfig) updateUserHandler(w http.ResponseWriter, r *http.Request) {
 	// expects:
-	// 1. an access token in the header
-	// 2. a new password and email in the request body
+	// 1. an access token, or a token with the profile:write scope, in the header
+	// 2. any of a new password (along with the current one), email and profile details in the request body -
+	//    personal access tokens and OAuth clients can only change the profile details
 	type parameters struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+		CurrentPassword string  `json:"current_password"`
+		Password        string  `json:"password"`
+		Email           string  `json:"email"`
+		Handle          *string `json:"handle"`
+		DisplayName     *string `json:"display_name"`
+		Bio             *string `json:"bio"`
 	}
 
-	// check access token
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Couldn't find JWT", err)
-		return
-	}
-
-	// unpack user ID
-	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
-	if err != nil {
-		respondWithError(w, http.StatusUnauthorized, "Unauthorized", err)
-		return
-	}
+	// the caller was checked by cfg.authenticate
+	userID := cfg.viewerID(r)
 
 	// decode request
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err := decoder.Decode(&params)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Couldn't decode parameters", err)
 		return
 	}
 
-	// hash new password
-	hashedNewPassword, err := auth.HashPassword(params.Password)
+	// keep the old details to compare against, and for the audit log
+	oldUser, err := cfg.db.GetUserByID(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error updating details", nil)
+		respondWithError(w, http.StatusNotFound, "User does not exist", err)
 		return
 	}
 
-	// update user details
-	updatedUser, err := cfg.db.UpdateUser(r.Context(), database.UpdateUserParams{
-		ID:             userID,
-		HashedPassword: hashedNewPassword,
-		Email:          params.Email,
-	})
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error updating record", nil)
+	// scoped tokens can edit the profile, but never the credentials protecting the account
+	caller, _ := cfg.viewer(r)
+	if caller.Scopes != nil && (params.Password != "" || params.Email != "") {
+		respondWithError(w, http.StatusForbidden, "Tokens can't change the password or email", nil)
 		return
 	}
 
+	// validate details up front so nothing is half updated. A new password needs the current one, or an
+	// access token alone could be used to get around everything else that asks for the password.
+	passwordChanged := params.Password != ""
+	if passwordChanged {
+		err = auth.CheckPasswordHash(params.CurrentPassword, oldUser.HashedPassword)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Incorrect password", err)
+			return
+		}
+		err = cfg.passwords.Validate(params.Password)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	emailChanged := params.Email != "" && params.Email != oldUser.Email
+	if emailChanged {
+		err = validateEmail(params.Email)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+		_, err = cfg.db.GetUserByEmail(r.Context(), params.Email)
+		if err == nil {
+			respondWithError(w, http.StatusConflict, "Email is already taken", nil)
+			return
+		}
+	}
+	if params.Handle != nil && *params.Handle != "" {
+		err = validateHandle(*params.Handle)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	if params.DisplayName != nil {
+		err = validateDisplayName(*params.DisplayName)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	if params.Bio != nil {
+		err = validateBio(*params.Bio)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	updatedUser := oldUser
+	if passwordChanged {
+		// hash new password
+		hashedNewPassword, err := auth.HashPassword(params.Password)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating details", nil)
+			return
+		}
+
+		// update password
+		updatedUser, err = cfg.db.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{
+			ID:             userID,
+			HashedPassword: hashedNewPassword,
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating record", nil)
+			return
+		}
+
+		// whoever knew the old password may still be signed in, or have made tokens of their own
+		err = cfg.db.RevokeUserTokens(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke sessions", err)
+			return
+		}
+		err = cfg.db.RevokeUserPersonalAccessTokens(r.Context(), userID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Failed to revoke personal access tokens", err)
+			return
+		}
+
+		cfg.recordAudit(r, auditEvent{
+			actorID:    userID,
+			action:     auditPasswordChanged,
+			targetType: auditTargetUser,
+			targetID:   userID.String(),
+			diff:       map[string]any{"password": auditChange{From: "[redacted]", To: "[redacted]"}},
+		})
+	}
+
+	// a new email is only pending until it is confirmed from the new address
+	if emailChanged {
+		updatedUser, err = cfg.db.SetPendingEmail(r.Context(), database.SetPendingEmailParams{
+			ID:           userID,
+			PendingEmail: sql.NullString{String: params.Email, Valid: true},
+		})
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating record", err)
+			return
+		}
+
+		cfg.sendVerificationEmailInBackground(r, userID, params.Email)
+		cfg.sendEmailChangeNoticeInBackground(r, oldUser.Email, params.Email)
+
+		cfg.recordAudit(r, auditEvent{
+			actorID:    userID,
+			action:     auditEmailChangeRequested,
+			targetType: auditTargetUser,
+			targetID:   userID.String(),
+			diff:       map[string]any{"pending_email": auditChange{From: oldUser.PendingEmail.String, To: params.Email}},
+		})
+	}
+
+	// update profile - fields left out are kept, and an empty handle clears it
+	if params.Handle != nil || params.DisplayName != nil || params.Bio != nil {
+		profile := database.UpdateUserProfileParams{
+			ID:          userID,
+			Handle:      updatedUser.Handle,
+			DisplayName: updatedUser.DisplayName,
+			Bio:         updatedUser.Bio,
+		}
+		if params.Handle != nil {
+			profile.Handle = sql.NullString{String: *params.Handle, Valid: *params.Handle != ""}
+		}
+		if params.DisplayName != nil {
+			profile.DisplayName = *params.DisplayName
+		}
+		if params.Bio != nil {
+			profile.Bio = *params.Bio
+		}
+
+		updatedUser, err = cfg.db.UpdateUserProfile(r.Context(), profile)
+		if isUniqueViolation(err, "users_handle_idx") {
+			respondWithError(w, http.StatusConflict, "Handle is already taken", err)
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error updating record", err)
+			return
+		}
+	}
+
 	// success!
-	respondWithJSON(w, http.StatusOK, User{
-		ID:        updatedUser.ID,
-		CreatedAt: updatedUser.CreatedAt,
-		UpdatedAt: updatedUser.UpdatedAt,
-		Email:     updatedUser.Email,
-		IsPremium: updatedUser.IsPremium,
-	})
+	respondWithJSON(w, http.StatusOK, mapUser(updatedUser))
+}
+
+// mapUser converts a database user into its json representation
+func mapUser(u database.User) User {
+	return User{
+		ID:            u.ID,
+		CreatedAt:     u.CreatedAt,
+		UpdatedAt:     u.UpdatedAt,
+		Email:         u.Email,
+		EmailVerified: u.EmailVerifiedAt.Valid,
+		PendingEmail:  u.PendingEmail.String,
+		IsPremium:     u.IsPremium,
+		Role:          u.Role,
+		Handle:        u.Handle.String,
+		DisplayName:   u.DisplayName,
+		Bio:           u.Bio,
+	}
+}
+
+// isUniqueViolation reports whether err was caused by a clash on the given unique constraint or index
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "23505" && pqErr.Constraint == constraint
 }